@@ -0,0 +1,274 @@
+package gojwe
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+// appendCodec is implemented by the ChaCha20 / XChaCha20 algorithms to build
+// and open tokens inside caller-owned buffers without heap allocations.
+type appendCodec interface {
+	// appendGenerate appends a token for the JSON payload to dst.
+	appendGenerate(dst, payload []byte, key *PreparedKey) ([]byte, error)
+	// decryptInPlace verifies and decrypts token, overwriting it, and returns
+	// the JSON payload as a sub-slice of token.
+	decryptInPlace(token []byte, key *PreparedKey) ([]byte, error)
+}
+
+// AppendGenerate encrypts already-marshalled JSON payload bytes and appends the
+// token to dst, returning the extended slice. With a buffer of sufficient
+// capacity, ChaCha20 and XChaCha20 tokens are produced without any heap
+// allocation; other algorithms fall back to GenerateWithKey. payload must not
+// overlap dst.
+//
+//	buf = buf[:0]
+//	buf, err = gojwe.AppendGenerate(j, buf, payload, key)
+func AppendGenerate(j JWE, dst []byte, payload []byte, key *PreparedKey) ([]byte, error) {
+	if ac, ok := j.(appendCodec); ok {
+		return ac.appendGenerate(dst, payload, key)
+	}
+	var token string
+	var err error
+	if pc, ok := j.(preparedCodec); ok {
+		token, err = pc.generatePrepared(payload, key)
+	} else {
+		// Fallback for custom JWE implementations that only expose Generate.
+		m := map[string]any{}
+		if err = json.Unmarshal(payload, &m); err != nil {
+			return dst, err
+		}
+		token, err = j.Generate(m, key.key)
+	}
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, token...), nil
+}
+
+// ParseBytes verifies and decrypts token, validates its registered claims like
+// Parse and returns the JSON payload. For ChaCha20 and XChaCha20 the token is
+// decoded and decrypted in place: the returned payload aliases token, whose
+// contents are overwritten. Decryption does not allocate; claim validation, when
+// enabled, costs one small decode of the registered claims.
+func ParseBytes(j JWE, token []byte, key *PreparedKey) ([]byte, error) {
+	pc, ok := j.(preparedCodec)
+	if !ok {
+		// Fallback for custom JWE implementations that only expose Parse.
+		m, err := j.Parse(string(token), key.key)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(m)
+	}
+
+	// The token is destroyed by in-place decryption, so keep a copy when a
+	// revocation check needs its fingerprint.
+	opts := pc.getOptions()
+	var tokenStr string
+	if opts.revoker != nil {
+		tokenStr = string(token)
+	}
+
+	var payload []byte
+	var err error
+	if ac, ok := j.(appendCodec); ok {
+		payload, err = ac.decryptInPlace(token, key)
+	} else {
+		payload, err = pc.decryptPrepared(string(token), key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validateParsedClaims(nil, payload, tokenStr, opts); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// signatureB64Len is the length of a base64url-encoded HMAC-SHA256 signature.
+const signatureB64Len = 43
+
+// ChaCha header layout written by appendChaChaToken; it matches encodeHeaderB64.
+const (
+	chachaHeaderAlg = `{"alg":"dir","enc":"`
+	chachaHeaderIv  = `","iv":"`
+	chachaHeaderTag = `","tag":"`
+	chachaHeaderEnd = `"}`
+)
+
+// appendChaChaToken builds a ChaCha20 / XChaCha20 token directly into dst. The
+// spare capacity after the token doubles as scratch space for the nonce, the
+// sealed payload and the header JSON, so nothing is allocated once dst is large
+// enough.
+func appendChaChaToken(dst, payload []byte, key *PreparedKey, enc string, r io.Reader) ([]byte, error) {
+	b64 := base64.RawURLEncoding
+	nonceSize, overhead := key.aead.NonceSize(), key.aead.Overhead()
+
+	ivLen, tagLen := b64.EncodedLen(nonceSize), b64.EncodedLen(overhead)
+	headerLen := len(chachaHeaderAlg) + len(enc) + len(chachaHeaderIv) + ivLen + len(chachaHeaderTag) + tagLen + len(chachaHeaderEnd)
+	headerB64Len, cipherB64Len := b64.EncodedLen(headerLen), b64.EncodedLen(len(payload))
+	tokenLen := headerB64Len + 1 + cipherB64Len + 1 + signatureB64Len
+	scratchLen := nonceSize + len(payload) + overhead + headerLen
+
+	start := len(dst)
+	if need := start + tokenLen + scratchLen; cap(dst) < need {
+		grown := make([]byte, start, need)
+		copy(grown, dst)
+		dst = grown
+	}
+	out := dst[:start+tokenLen+scratchLen]
+	token, scratch := out[start:start+tokenLen], out[start+tokenLen:]
+
+	// Generate the nonce and encrypt the payload into the scratch space
+	nonce := scratch[:nonceSize]
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return dst, err
+	}
+	sealed := key.aead.Seal(scratch[nonceSize:nonceSize], nonce, payload, nil)
+	ciphertext, tag := sealed[:len(payload)], sealed[len(payload):]
+
+	// Build the header JSON (alg=dir, enc, iv, tag) after the sealed payload
+	header := scratch[nonceSize+len(sealed):][:0]
+	header = append(header, chachaHeaderAlg...)
+	header = append(header, enc...)
+	header = append(header, chachaHeaderIv...)
+	header = header[:len(header)+ivLen]
+	b64.Encode(header[len(header)-ivLen:], nonce)
+	header = append(header, chachaHeaderTag...)
+	header = header[:len(header)+tagLen]
+	b64.Encode(header[len(header)-tagLen:], tag)
+	header = append(header, chachaHeaderEnd...)
+
+	// header.ciphertext
+	b64.Encode(token, header)
+	token[headerB64Len] = '.'
+	b64.Encode(token[headerB64Len+1:], ciphertext)
+	signed := headerB64Len + 1 + cipherB64Len
+	token[signed] = '.'
+
+	// .signature
+	m := key.getMAC()
+	m.h.Write(token[:signed])
+	b64.Encode(token[signed+1:], m.h.Sum(m.sum[:0]))
+	key.putMAC(m)
+
+	return out[:start+tokenLen], nil
+}
+
+// Separator and header members located by openChaChaTokenInPlace.
+var (
+	dot             = []byte{'.'}
+	headerIvMember  = []byte(`"iv":"`)
+	headerTagMember = []byte(`"tag":"`)
+)
+
+// openChaChaTokenInPlace verifies and decrypts a ChaCha20 / XChaCha20 token,
+// decoding every part in place. The signature is checked before anything is
+// decoded, since decoding destroys the signed bytes.
+func openChaChaTokenInPlace(token []byte, key *PreparedKey) ([]byte, error) {
+	if len(token) > MaxTokenBytes {
+		return nil, sizeError(len(token))
+	}
+	if n := bytes.Count(token, dot) + 1; n != 3 {
+		return nil, tokenError(StageSize, ErrInvalidToken, segmentsError(n, 3))
+	}
+	dot1 := bytes.IndexByte(token, '.')
+	dot2 := dot1 + 1 + bytes.IndexByte(token[dot1+1:], '.')
+
+	// Verify signature using a constant-time comparison to avoid timing attacks
+	m := key.getMAC()
+	m.h.Write(token[:dot2])
+	base64.RawURLEncoding.Encode(m.sig[:], m.h.Sum(m.sum[:0]))
+	valid := hmac.Equal(token[dot2+1:], m.sig[:])
+	key.putMAC(m)
+	if !valid {
+		return nil, tokenError(StageSignature, ErrInvalidSignature, nil)
+	}
+
+	// Decode the header, then its nonce and tag, in place
+	header, err := decodeInPlace(token[:dot1])
+	if err != nil {
+		return nil, tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	nonce, tag, err := headerNonceAndTag(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != key.aead.NonceSize() {
+		return nil, tokenError(StageHeader, ErrInvalidToken, lengthError("nonce", len(nonce), key.aead.NonceSize()))
+	}
+	if len(tag) != key.aead.Overhead() {
+		return nil, tokenError(StageSignature, ErrInvalidToken, lengthError("tag", len(tag), key.aead.Overhead()))
+	}
+
+	// Decode the ciphertext in place and move the tag right behind it; the
+	// already-verified signature leaves enough room.
+	ciphertext, err := decodeInPlace(token[dot1+1 : dot2])
+	if err != nil {
+		return nil, tokenError(StageDecrypt, ErrInvalidToken, err)
+	}
+	sealed := token[dot1+1 : dot1+1+len(ciphertext)+len(tag)]
+	copy(sealed[len(ciphertext):], tag)
+
+	plaintext, err := key.aead.Open(sealed[:0], nonce, sealed, nil)
+	if err != nil {
+		return nil, tokenError(StageSignature, ErrInvalidSignature, err)
+	}
+	return plaintext, nil
+}
+
+// headerNonceAndTag extracts and decodes the "iv" and "tag" members of a
+// decoded ChaCha header, in place when it has the compact layout written by
+// this package. Errors are *TokenErrors: StageHeader for the header and
+// nonce, StageSignature for the tag.
+func headerNonceAndTag(header []byte) (nonce, tag []byte, err error) {
+	ivB64, tagB64 := headerMember(header, headerIvMember), headerMember(header, headerTagMember)
+	if ivB64 == nil || tagB64 == nil {
+		// Another layout (e.g. whitespace from a different encoder): decode it
+		// properly, at the cost of a few allocations.
+		var h Header
+		if err := json.Unmarshal(header, &h); err != nil {
+			return nil, nil, tokenError(StageHeader, ErrInvalidToken, err)
+		}
+		if nonce, err = base64.RawURLEncoding.DecodeString(h.Iv); err != nil {
+			return nil, nil, tokenError(StageHeader, ErrInvalidToken, err)
+		}
+		if tag, err = base64.RawURLEncoding.DecodeString(h.Tag); err != nil {
+			return nil, nil, tokenError(StageSignature, ErrInvalidToken, err)
+		}
+		return nonce, tag, nil
+	}
+	if nonce, err = decodeInPlace(ivB64); err != nil {
+		return nil, nil, tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	if tag, err = decodeInPlace(tagB64); err != nil {
+		return nil, nil, tokenError(StageSignature, ErrInvalidToken, err)
+	}
+	return nonce, tag, nil
+}
+
+// decodeInPlace base64url-decodes b into itself. The decoder never writes ahead
+// of the input it has consumed, so sharing the buffer is safe.
+func decodeInPlace(b []byte) ([]byte, error) {
+	n, err := base64.RawURLEncoding.Decode(b, b)
+	return b[:n], err
+}
+
+// headerMember returns the raw string value of a member of a compact JSON
+// header, or nil when the member is not found in that form.
+func headerMember(header, member []byte) []byte {
+	i := bytes.Index(header, member)
+	if i < 0 {
+		return nil
+	}
+	value := header[i+len(member):]
+	end := bytes.IndexByte(value, '"')
+	if end < 0 {
+		return nil
+	}
+	return value[:end]
+}
@@ -0,0 +1,144 @@
+package gojwe_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+// raceEnabled is set by race_test.go when built with -race.
+var raceEnabled bool
+
+func TestAppendGenerateMatchesGenerate(t *testing.T) {
+	const want = "eyJhbGciOiJkaXIiLCJlbmMiOiJDMjBQIiwiaXYiOiJBQUVDQXdRRkJnY0lDUW9MIiwidGFnIjoiNlZDMVNDUjRBOGs0VmFFV29yNmI5ZyJ9.z6fPYd0hwcVFseSOg7YheQvX-w.hKk2kpWhLPsXYYyF36woqMuZVKul7I6rCPSoEBabyXY"
+
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithRand(&countingReader{}))
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, chaCha20Key)
+	got, err := gojwe.AppendGenerate(j, []byte("prefix:"), []byte(`{"exp":99999999999}`), key)
+	if err != nil {
+		t.Fatalf("AppendGenerate() error = %v", err)
+	}
+	if string(got) != "prefix:"+want {
+		t.Fatalf("AppendGenerate() = %s, want prefix:%s", got, want)
+	}
+}
+
+func TestAppendGenerateAndParseBytes(t *testing.T) {
+	raw := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		j := gojwe.New(alg)
+		genRaw, parseRaw := keysFor(alg, raw)
+		genKey, _ := gojwe.PrepareKey(alg, genRaw)
+		parseKey, _ := gojwe.PrepareKey(alg, parseRaw)
+
+		// Cover every base64 tail length when decoding in place.
+		for n := 0; n < 64; n++ {
+			payload := []byte(`{"sub":"` + strings.Repeat("x", n) + `"}`)
+			token, err := gojwe.AppendGenerate(j, nil, payload, genKey)
+			if err != nil {
+				t.Fatalf("[%s] AppendGenerate() error = %v", alg, err)
+			}
+			if _, err := j.Parse(string(token), parseRaw); err != nil {
+				t.Fatalf("[%s] Parse() of appended token error = %v", alg, err)
+			}
+
+			got, err := gojwe.ParseBytes(j, token, parseKey)
+			if err != nil {
+				t.Fatalf("[%s] ParseBytes() error = %v", alg, err)
+			}
+			if string(got) != string(payload) {
+				t.Fatalf("[%s] ParseBytes() = %s, want %s", alg, got, payload)
+			}
+		}
+	}
+}
+
+func TestParseBytesRejects(t *testing.T) {
+	raw := gojwe.MustGenerateKey()
+	for _, alg := range []string{gojwe.ChaCha20, gojwe.XChaCha20} {
+		j := gojwe.New(alg)
+		key, _ := gojwe.PrepareKey(alg, raw)
+
+		expired, _ := j.Generate(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, raw)
+		if _, err := gojwe.ParseBytes(j, []byte(expired), key); !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Fatalf("[%s] ParseBytes() error = %v, want ErrTokenExpired", alg, err)
+		}
+
+		token, _ := j.Generate(map[string]any{"sub": "x"}, raw)
+		tampered := []byte(token)
+		tampered[len(tampered)/2] ^= 1
+		if _, err := gojwe.ParseBytes(j, tampered, key); !errors.Is(err, gojwe.ErrInvalidSignature) {
+			t.Fatalf("[%s] ParseBytes() error = %v, want ErrInvalidSignature", alg, err)
+		}
+		if _, err := gojwe.ParseBytes(j, []byte("a.b"), key); !errors.Is(err, gojwe.ErrInvalidToken) {
+			t.Fatalf("[%s] ParseBytes() error = %v, want ErrInvalidToken", alg, err)
+		}
+	}
+}
+
+func TestAppendGenerateZeroAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable under -race")
+	}
+	for _, alg := range []string{gojwe.ChaCha20, gojwe.XChaCha20} {
+		j := gojwe.New(alg)
+		key, _ := gojwe.PrepareKey(alg, chaCha20Key)
+		payload := []byte(`{"sub":"user-1","exp":99999999999}`)
+		buf := make([]byte, 0, 1024)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			buf, _ = gojwe.AppendGenerate(j, buf[:0], payload, key)
+		})
+		if allocs != 0 {
+			t.Fatalf("[%s] AppendGenerate() allocs = %v, want 0", alg, allocs)
+		}
+	}
+}
+
+func TestParseBytesZeroAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable under -race")
+	}
+	for _, alg := range []string{gojwe.ChaCha20, gojwe.XChaCha20} {
+		j := gojwe.New(alg, gojwe.WithoutTimeValidation())
+		key, _ := gojwe.PrepareKey(alg, chaCha20Key)
+		token, _ := gojwe.AppendGenerate(j, nil, []byte(`{"sub":"user-1"}`), key)
+		buf := make([]byte, len(token))
+
+		allocs := testing.AllocsPerRun(100, func() {
+			copy(buf, token)
+			if _, err := gojwe.ParseBytes(j, buf, key); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs != 0 {
+			t.Fatalf("[%s] ParseBytes() allocs = %v, want 0", alg, allocs)
+		}
+	}
+}
+
+func BenchmarkChaCha20AppendGenerate(b *testing.B) {
+	j := gojwe.New(gojwe.ChaCha20)
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, chaCha20Key)
+	payload := []byte(`{"exp":999999999}`)
+	buf := make([]byte, 0, 512)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = gojwe.AppendGenerate(j, buf[:0], payload, key)
+	}
+}
+
+func BenchmarkChaCha20ParseBytes(b *testing.B) {
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithoutTimeValidation())
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, chaCha20Key)
+	jwe := "eyJhbGciOiJkaXIiLCJlbmMiOiJDMjBQIiwiaXYiOiIyU0M3aFhtQTVScGxIZllwIiwidGFnIjoiWjV6dVB1REZLVDU4LTZqajFiVENIUSJ9.8N8nO9Oh0jpWv2aXQRz9qrUKIw.q7tqbWfPsWTI97wP6xPSeoJTcPiBlVDNzOFmp4qXNlo"
+	buf := make([]byte, len(jwe))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		copy(buf, jwe)
+		_, _ = gojwe.ParseBytes(j, buf, key)
+	}
+}
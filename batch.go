@@ -0,0 +1,152 @@
+package gojwe
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// GenerateResult is the outcome of one item of GenerateBatch.
+type GenerateResult struct {
+	Token string
+	Err   error
+}
+
+// ParseResult is the outcome of one item of ParseBatch.
+type ParseResult struct {
+	Claims map[string]any
+	Err    error
+}
+
+// BatchOption configures GenerateBatch / ParseBatch.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	workers int
+}
+
+// WithWorkers sets the number of goroutines processing a batch. It defaults to
+// runtime.GOMAXPROCS(0).
+func WithWorkers(n int) BatchOption {
+	return func(o *batchOptions) { o.workers = n }
+}
+
+// GenerateBatch encrypts every payload with j and key on a bounded worker pool.
+// Results are returned in input order, each with its own error (the same
+// sentinel errors as Generate). The key is prepared once and shared by all
+// workers.
+//
+// The returned error is non-nil only for batch-wide failures: an invalid key,
+// or ctx being cancelled, in which case the items that were not processed carry
+// ctx.Err().
+func GenerateBatch(ctx context.Context, j JWE, payloads []map[string]any, key []byte, opts ...BatchOption) ([]GenerateResult, error) {
+	prepared, err := prepareForBatch(j, key)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]GenerateResult, len(payloads))
+	err = runBatch(ctx, len(payloads), opts, func(i int) {
+		if prepared != nil {
+			results[i].Token, results[i].Err = GenerateWithKey(j, payloads[i], prepared)
+		} else {
+			results[i].Token, results[i].Err = j.Generate(payloads[i], key)
+		}
+	}, func(i int, err error) { results[i].Err = err })
+	return results, err
+}
+
+// ParseBatch decrypts and validates every token with j and key on a bounded
+// worker pool. Results are returned in input order, each with its own error
+// (the same sentinel errors as Parse). The key is prepared once and shared by
+// all workers.
+//
+// The returned error is non-nil only for batch-wide failures: an invalid key,
+// or ctx being cancelled, in which case the items that were not processed carry
+// ctx.Err().
+func ParseBatch(ctx context.Context, j JWE, tokens []string, key []byte, opts ...BatchOption) ([]ParseResult, error) {
+	prepared, err := prepareForBatch(j, key)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ParseResult, len(tokens))
+	err = runBatch(ctx, len(tokens), opts, func(i int) {
+		if prepared != nil {
+			results[i].Claims, results[i].Err = ParseWithKey(j, tokens[i], prepared)
+		} else {
+			results[i].Claims, results[i].Err = j.Parse(tokens[i], key)
+		}
+	}, func(i int, err error) { results[i].Err = err })
+	return results, err
+}
+
+// prepareForBatch prepares key for the algorithm of a built-in JWE. It returns
+// a nil key for custom implementations, which then receive the raw key.
+func prepareForBatch(j JWE, key []byte) (*PreparedKey, error) {
+	alg := algorithmOf(j)
+	if alg == "" {
+		return nil, nil
+	}
+	return PrepareKey(alg, key)
+}
+
+// algorithmOf returns the algorithm name of a built-in JWE, or "" for custom
+// implementations.
+func algorithmOf(j JWE) string {
+	switch j.(type) {
+	case *JweAesGcm256:
+		return AESGCM256
+	case *JweChaCha20:
+		return ChaCha20
+	case *JweXChaCha20:
+		return XChaCha20
+	case *JweHPKE:
+		return HPKE
+	}
+	return ""
+}
+
+// runBatch calls process for every index in [0, n) from a pool of workers,
+// stopping early when ctx is cancelled. Indices that were never processed are
+// passed to skip together with ctx.Err().
+func runBatch(ctx context.Context, n int, opts []BatchOption, process func(i int), skip func(i int, err error)) error {
+	o := batchOptions{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers > n {
+		o.workers = n
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+
+	done := make([]bool, n)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < o.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				process(i)
+				done[i] = true
+			}
+		}()
+	}
+	wg.Wait()
+
+	err := ctx.Err()
+	if err != nil {
+		for i, ok := range done {
+			if !ok {
+				skip(i, err)
+			}
+		}
+	}
+	return err
+}
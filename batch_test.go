@@ -0,0 +1,94 @@
+package gojwe_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestGenerateAndParseBatch(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg)
+
+		payloads := make([]map[string]any, 50)
+		for i := range payloads {
+			payloads[i] = map[string]any{"sub": fmt.Sprint("user-", i)}
+		}
+		// One expired token to check per-item errors.
+		payloads[7]["exp"] = time.Now().Add(-time.Hour).Unix()
+
+		generated, err := gojwe.GenerateBatch(context.Background(), j, payloads, genKey, gojwe.WithWorkers(4))
+		if err != nil {
+			t.Fatalf("[%s] GenerateBatch() error = %v", alg, err)
+		}
+		tokens := make([]string, len(generated))
+		for i, r := range generated {
+			if r.Err != nil {
+				t.Fatalf("[%s] GenerateBatch()[%d] error = %v", alg, i, r.Err)
+			}
+			tokens[i] = r.Token
+		}
+
+		parsed, err := gojwe.ParseBatch(context.Background(), j, tokens, parseKey, gojwe.WithWorkers(4))
+		if err != nil {
+			t.Fatalf("[%s] ParseBatch() error = %v", alg, err)
+		}
+		for i, r := range parsed {
+			if i == 7 {
+				if !errors.Is(r.Err, gojwe.ErrTokenExpired) {
+					t.Fatalf("[%s] ParseBatch()[7] error = %v, want ErrTokenExpired", alg, r.Err)
+				}
+				continue
+			}
+			if r.Err != nil || r.Claims["sub"] != fmt.Sprint("user-", i) {
+				t.Fatalf("[%s] ParseBatch()[%d] = %v, %v (order not preserved?)", alg, i, r.Claims, r.Err)
+			}
+		}
+	}
+}
+
+func TestParseBatchCancelled(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	token, _ := j.Generate(map[string]any{"sub": "x"}, key)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := gojwe.ParseBatch(ctx, j, []string{token, token, token}, key)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ParseBatch() error = %v, want context.Canceled", err)
+	}
+	for i, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Fatalf("ParseBatch()[%d] error = %v, want context.Canceled", i, r.Err)
+		}
+	}
+}
+
+func TestBatchInvalidKey(t *testing.T) {
+	j := gojwe.New(gojwe.XChaCha20)
+	if _, err := gojwe.ParseBatch(context.Background(), j, []string{"a.b.c"}, []byte("short")); !errors.Is(err, gojwe.ErrInvalidKeySize) {
+		t.Fatalf("ParseBatch() error = %v, want ErrInvalidKeySize", err)
+	}
+	if _, err := gojwe.GenerateBatch(context.Background(), j, []map[string]any{{}}, []byte("short")); !errors.Is(err, gojwe.ErrInvalidKeySize) {
+		t.Fatalf("GenerateBatch() error = %v, want ErrInvalidKeySize", err)
+	}
+}
+
+func BenchmarkChaCha20ParseBatch(b *testing.B) {
+	j := gojwe.New(gojwe.ChaCha20)
+	tokens := make([]string, 1000)
+	for i := range tokens {
+		tokens[i], _ = j.Generate(map[string]any{"exp": 99999999999}, chaCha20Key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = gojwe.ParseBatch(context.Background(), j, tokens, chaCha20Key)
+	}
+}
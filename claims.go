@@ -1,85 +1,241 @@
 package gojwe
 
-import "time"
+import (
+	"bytes"
+	"time"
 
-// nowFunc is overridable in tests; defaults to time.Now.
-var nowFunc = time.Now
+	"github.com/goccy/go-json"
+)
+
+// claimSet is the view of a token's registered claims that validation works
+// on, whether they were decoded into a map or into a typed struct. Absent time
+// claims are left as the zero time.Time.
+type claimSet struct {
+	exp, nbf, iat time.Time
+	iss, sub, jti string
+	aud           any // string, []any, []string or ClaimStrings
+
+	// token is the serialized token, for revocation fingerprints.
+	token string
+
+	// claims is the full payload, for custom validators and required private
+	// claims. On the typed paths it is decoded from raw on first use, and value
+	// holds the typed claims.
+	claims map[string]any
+	raw    []byte
+	value  any
+
+	// exact is the payload decoded from raw with json.Number numbers, for
+	// claim constraints.
+	exact map[string]any
+}
+
+// mapClaimSet builds the claim view of a claims map decoded from raw, with
+// times read at precision (see toUnixTime).
+func mapClaimSet(claims map[string]any, raw []byte, token string, precision time.Duration) *claimSet {
+	c := &claimSet{aud: claims["aud"], token: token, claims: claims, raw: raw}
+	c.exp, _ = toUnixTime(claims["exp"], precision)
+	c.nbf, _ = toUnixTime(claims["nbf"], precision)
+	c.iat, _ = toUnixTime(claims["iat"], precision)
+	c.iss, _ = claims["iss"].(string)
+	c.sub, _ = claims["sub"].(string)
+	c.jti, _ = claims["jti"].(string)
+	return c
+}
+
+// payload returns the full decoded payload, decoding raw on first use.
+func (c *claimSet) payload() (map[string]any, error) {
+	if c.claims == nil {
+		claims := map[string]any{}
+		if err := json.Unmarshal(c.raw, &claims); err != nil {
+			return nil, err
+		}
+		c.claims = claims
+	}
+	return c.claims, nil
+}
+
+// exactPayload returns the full payload with numbers decoded as json.Number, so
+// that large integers keep every digit. It falls back to payload when raw is
+// not available.
+func (c *claimSet) exactPayload() (map[string]any, error) {
+	if c.raw == nil {
+		return c.payload()
+	}
+	if c.exact == nil {
+		claims := map[string]any{}
+		dec := json.NewDecoder(bytes.NewReader(c.raw))
+		dec.UseNumber()
+		if err := dec.Decode(&claims); err != nil {
+			return nil, err
+		}
+		c.exact = claims
+	}
+	return c.exact, nil
+}
 
 // validateClaims validates the standard registered claims (exp, nbf, iat, iss,
 // aud) of a decoded claims map according to opts. Missing claims are treated as
 // "no constraint", except for iss/aud which, when required via options, must be
-// present and match.
-func validateClaims(claims map[string]any, opts options) error {
-	now := nowFunc()
+// present and match, and jti which replay protection requires. Functions added
+// with WithValidator then run on the whole map. token is the serialized token
+// the claims came from, used for revocation fingerprints, and raw its decrypted
+// payload.
+func validateClaims(claims map[string]any, raw []byte, token string, opts options) error {
+	if !opts.needsValidation() {
+		return nil
+	}
+	return claimsError(validate(mapClaimSet(claims, raw, token, opts.precision), opts))
+}
 
-	if opts.validateTime {
-		if exp, ok := toUnixTime(claims["exp"]); ok && now.After(exp.Add(opts.leeway)) {
-			return ErrTokenExpired
+// claimChecks are the stateless checks of validate, in order. Custom
+// validators run once the registered claims have been checked, and lookups in
+// external stores (revocation, session epochs) come after the cheap claim
+// checks. Each reports failures to the validation and returns only errors that
+// prevent validating, such as an unreachable store.
+var claimChecks = []func(c *claimSet, opts options, v *validation) error{
+	checkTime,
+	checkRequired,
+	checkTags,
+	checkMaxAge,
+	checkIssuer,
+	checkAudience,
+	checkConstraints,
+	checkGrants,
+	checkCustom,
+	checkRevoked,
+	checkSessionEpoch,
+}
+
+// validate runs every check configured in opts against c. It stops at the
+// first failure unless WithAllValidationErrors is set, and returns the failures
+// as a *ValidationError. The stateful replay check runs last and only when
+// everything else passed, so a token is only recorded as used once it is
+// otherwise valid.
+func validate(c *claimSet, opts options) error {
+	v := &validation{all: opts.allValidationErrors, now: opts.now()}
+	for _, check := range claimChecks {
+		if err := check(c, opts, v); err != nil {
+			return err
 		}
-		if nbf, ok := toUnixTime(claims["nbf"]); ok && now.Add(opts.leeway).Before(nbf) {
-			return ErrTokenNotYetValid
+		if v.stopped() {
+			return v.err()
 		}
-		if opts.validateIat {
-			if iat, ok := toUnixTime(claims["iat"]); ok && iat.After(now.Add(opts.leeway)) {
-				return ErrTokenUsedBeforeIssued
-			}
+	}
+	if len(v.failures) == 0 && opts.replayStore != nil {
+		if err := checkReplay(c, opts, v); err != nil {
+			return err
 		}
 	}
+	return v.err()
+}
 
-	if opts.expectedIss != "" {
-		iss, _ := claims["iss"].(string)
-		if iss != opts.expectedIss {
-			return ErrInvalidIssuer
+// checkTime validates exp, nbf and, with WithIssuedAtValidation, iat against
+// the current time and leeway.
+func checkTime(c *claimSet, opts options, v *validation) error {
+	if !opts.validateTime {
+		return nil
+	}
+	if !c.exp.IsZero() {
+		if late := v.now.Sub(c.exp.Add(opts.leeway + opts.expiryGrace)); late > 0 &&
+			v.fail(&ClaimError{Claim: "exp", Expected: v.now, Actual: c.exp, Delta: late, Err: ErrTokenExpired}) {
+			return nil
+		}
+	}
+	if !c.nbf.IsZero() {
+		if early := c.nbf.Sub(v.now.Add(opts.leeway)); early > 0 &&
+			v.fail(&ClaimError{Claim: "nbf", Expected: v.now, Actual: c.nbf, Delta: early, Err: ErrTokenNotYetValid}) {
+			return nil
 		}
 	}
-	if opts.expectedAud != "" && !audienceContains(claims["aud"], opts.expectedAud) {
-		return ErrInvalidAudience
+	if opts.validateIat && !c.iat.IsZero() {
+		if early := c.iat.Sub(v.now.Add(opts.leeway)); early > 0 {
+			v.fail(&ClaimError{Claim: "iat", Expected: v.now, Actual: c.iat, Delta: early, Err: ErrTokenUsedBeforeIssued})
+		}
 	}
 	return nil
 }
 
-// audienceContains reports whether the raw "aud" claim (a string or an array of
-// strings, as produced by JSON decoding) contains want.
-func audienceContains(v any, want string) bool {
-	switch a := v.(type) {
-	case string:
-		return a == want
-	case []any:
-		for _, item := range a {
-			if s, ok := item.(string); ok && s == want {
-				return true
-			}
-		}
-	case []string:
-		for _, s := range a {
-			if s == want {
-				return true
-			}
-		}
-	case ClaimStrings:
-		for _, s := range a {
-			if s == want {
-				return true
-			}
-		}
+// checkIssuer enforces WithIssuer and WithIssuers.
+func checkIssuer(c *claimSet, opts options, v *validation) error {
+	if len(opts.issuers) > 0 && !issuerAccepted(c.iss, opts) {
+		v.fail(&ClaimError{Claim: "iss", Expected: opts.issuers, Actual: presentString(c.iss), Err: ErrInvalidIssuer})
+	}
+	return nil
+}
+
+// checkAudience enforces the audience options.
+func checkAudience(c *claimSet, opts options, v *validation) error {
+	if len(opts.audiences) == 0 || audienceAccepted(c.aud, opts) {
+		return nil
+	}
+	var actual any
+	if audiencePresent(c.aud) {
+		actual = c.aud
+	}
+	v.fail(&ClaimError{Claim: "aud", Expected: opts.audiences, Actual: actual, Err: ErrInvalidAudience})
+	return nil
+}
+
+// checkRevoked consults the Revoker configured with WithRevoker.
+func checkRevoked(c *claimSet, opts options, v *validation) error {
+	if opts.revoker == nil {
+		return nil
+	}
+	revoked, err := opts.revoker.IsRevoked(c.jti, Fingerprint(c.token), v.now)
+	if err != nil {
+		return err
 	}
-	return false
+	if revoked {
+		v.fail(&ClaimError{Err: ErrTokenRevoked})
+	}
+	return nil
 }
 
 // toUnixTime interprets a numeric claim value as seconds since the Unix epoch.
-// JSON numbers decode to float64, but int variants are handled for callers that
-// build claims maps directly.
-func toUnixTime(v any) (time.Time, bool) {
+// JSON numbers decode to float64, or json.Number when decoded with UseNumber;
+// integer types, time.Time and NumericDate are handled for callers that build
+// claims maps directly.
+//
+// With a zero precision, JSON numbers are read as whole seconds and times are
+// kept as they are; otherwise every value keeps its fraction, truncated to
+// precision (see WithTimePrecision).
+func toUnixTime(v any, precision time.Duration) (time.Time, bool) {
+	var t time.Time
 	switch n := v.(type) {
 	case float64:
-		return time.Unix(int64(n), 0), true
-	case int64:
-		return time.Unix(n, 0), true
-	case int:
-		return time.Unix(int64(n), 0), true
-	case int32:
-		return time.Unix(int64(n), 0), true
+		if precision <= 0 {
+			return time.Unix(int64(n), 0), true
+		}
+		t = floatTime(n)
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return time.Unix(i, 0), true
+		}
+		var err error
+		if t, err = parseNumericDate(string(n)); err != nil {
+			return time.Time{}, false
+		}
+		if precision <= 0 {
+			return time.Unix(t.Unix(), 0), true
+		}
+	case time.Time:
+		t = n
+	case *NumericDate:
+		if n == nil {
+			return time.Time{}, false
+		}
+		t = n.Time
+	case NumericDate:
+		t = n.Time
 	default:
+		if i, ok := toInt64(v); ok {
+			return time.Unix(i, 0), true
+		}
 		return time.Time{}, false
 	}
+	if precision > 0 {
+		t = t.Truncate(precision)
+	}
+	return t, true
 }
@@ -0,0 +1,74 @@
+package gojwe
+
+import (
+	"bytes"
+
+	"github.com/goccy/go-json"
+)
+
+// registeredClaimNames are the JSON members held by RegisteredClaims.
+var registeredClaimNames = [...]string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// Claims are RegisteredClaims plus every other member of the claims set,
+// collected in Extra. Unlike RegisteredClaims on its own it loses nothing, so
+// a proxy can parse a token, act on the registered claims and reissue it with
+// the private claims it does not understand passed through untouched:
+//
+//	claims, err := gojwe.ParseClaims[gojwe.Claims](j, token, key)
+//	role, _ := claims.Extra["role"].(string)
+//	token, err = gojwe.GenerateClaims(j, claims, key)
+//
+// Numbers in Extra decode as json.Number, so they are reissued exactly.
+type Claims struct {
+	RegisteredClaims
+
+	// Extra holds the members that are not registered claims. On marshal,
+	// entries named like a registered claim are ignored when that field is
+	// set.
+	Extra map[string]any `json:"-"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, merging Extra with the
+// registered claims into one object.
+func (c Claims) MarshalJSON() ([]byte, error) {
+	registered, err := json.Marshal(c.RegisteredClaims)
+	if err != nil || len(c.Extra) == 0 {
+		return registered, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(registered, &fields); err != nil {
+		return nil, err
+	}
+	out := make(map[string]any, len(c.Extra)+len(fields))
+	for name, v := range c.Extra {
+		out[name] = v
+	}
+	for name, v := range fields {
+		out[name] = v
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, filling the
+// registered claims and collecting all other members in Extra. Extra is nil
+// when there are none.
+func (c *Claims) UnmarshalJSON(b []byte) error {
+	var registered RegisteredClaims
+	if err := json.Unmarshal(b, &registered); err != nil {
+		return err
+	}
+	var extra map[string]any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&extra); err != nil {
+		return err
+	}
+	for _, name := range registeredClaimNames {
+		delete(extra, name)
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	*c = Claims{RegisteredClaims: registered, Extra: extra}
+	return nil
+}
@@ -0,0 +1,94 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/prongbang/gojwe"
+)
+
+func TestClaimsExtraRoundTrip(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg)
+		token, err := j.Generate(map[string]any{
+			"sub":    "user-1",
+			"exp":    exp.Unix(),
+			"aud":    []string{"api", "web"},
+			"role":   "admin",
+			"tenant": map[string]any{"id": 7},
+			"big":    json.Number("9007199254740993"),
+		}, genKey)
+		if err != nil {
+			t.Fatalf("[%s] Generate() error = %v", alg, err)
+		}
+
+		claims, err := gojwe.ParseClaims[gojwe.Claims](j, token, parseKey)
+		if err != nil {
+			t.Fatalf("[%s] ParseClaims() error = %v", alg, err)
+		}
+		if claims.Subject != "user-1" || !claims.ExpiresAt.Equal(exp) || len(claims.Audience) != 2 {
+			t.Fatalf("[%s] registered = %+v", alg, claims.RegisteredClaims)
+		}
+		if len(claims.Extra) != 3 || claims.Extra["role"] != "admin" || claims.Extra["big"] != json.Number("9007199254740993") {
+			t.Fatalf("[%s] Extra = %v", alg, claims.Extra)
+		}
+
+		// Pass the unknown claims through a reissue.
+		claims.Issuer = "proxy"
+		token, err = gojwe.GenerateClaims(j, claims, genKey)
+		if err != nil {
+			t.Fatalf("[%s] GenerateClaims() error = %v", alg, err)
+		}
+		m, err := gojwe.ParseClaims[map[string]any](j, token, parseKey)
+		if err != nil {
+			t.Fatalf("[%s] ParseClaims() error = %v", alg, err)
+		}
+		tenant, _ := m["tenant"].(map[string]any)
+		if m["iss"] != "proxy" || m["role"] != "admin" || tenant["id"] != float64(7) || m["sub"] != "user-1" {
+			t.Fatalf("[%s] reissued claims = %v", alg, m)
+		}
+	}
+}
+
+func TestClaimsMarshalPrefersRegisteredFields(t *testing.T) {
+	c := gojwe.Claims{
+		RegisteredClaims: gojwe.RegisteredClaims{Subject: "user-1"},
+		Extra:            map[string]any{"sub": "spoofed", "iss": "from-extra", "role": "admin"},
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["sub"] != "user-1" || got["iss"] != "from-extra" || got["role"] != "admin" {
+		t.Fatalf("Marshal() = %s", b)
+	}
+
+	var back gojwe.Claims
+	if err := json.Unmarshal([]byte(`{"sub":"user-1"}`), &back); err != nil || back.Extra != nil {
+		t.Fatalf("Unmarshal() Extra = %v, %v, want nil", back.Extra, err)
+	}
+}
+
+func TestClaimsValidation(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	token, err := gojwe.GenerateClaims(j, gojwe.Claims{
+		RegisteredClaims: gojwe.RegisteredClaims{ExpiresAt: gojwe.NewNumericDate(time.Now().Add(-time.Hour))},
+		Extra:            map[string]any{"role": "admin"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gojwe.ParseClaims[gojwe.Claims](j, token, key); !errors.Is(err, gojwe.ErrTokenExpired) {
+		t.Fatalf("ParseClaims() error = %v, want ErrTokenExpired", err)
+	}
+}
@@ -0,0 +1,96 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestWithClock(t *testing.T) {
+	t.Parallel()
+	key := gojwe.MustGenerateKey()
+	start := time.Unix(1700000000, 0)
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		clock := gojwe.NewFakeClock(start)
+		j := gojwe.New(alg, gojwe.WithClock(clock), gojwe.WithLeeway(0))
+
+		token, _ := j.Generate(map[string]any{
+			"nbf": start.Add(time.Minute).Unix(),
+			"exp": start.Add(time.Hour).Unix(),
+		}, genKey)
+
+		if _, err := j.Parse(token, parseKey); !errors.Is(err, gojwe.ErrTokenNotYetValid) {
+			t.Fatalf("[%s] Parse() error = %v, want ErrTokenNotYetValid", alg, err)
+		}
+		clock.Advance(time.Minute)
+		if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey); err != nil {
+			t.Fatalf("[%s] ParseClaims() error = %v", alg, err)
+		}
+		clock.Set(start.Add(2 * time.Hour))
+		if _, err := j.Parse(token, parseKey); !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Fatalf("[%s] Parse() error = %v, want ErrTokenExpired", alg, err)
+		}
+	}
+}
+
+func TestWithClockPerInstance(t *testing.T) {
+	t.Parallel()
+	key := gojwe.MustGenerateKey()
+	exp := time.Unix(1700003600, 0)
+
+	past := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(gojwe.NewFakeClock(exp.Add(-time.Hour))))
+	future := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(gojwe.NewFakeClock(exp.Add(time.Hour))))
+
+	token, _ := past.Generate(map[string]any{"exp": exp.Unix()}, key)
+	if _, err := past.Parse(token, key); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := future.Parse(token, key); !errors.Is(err, gojwe.ErrTokenExpired) {
+		t.Fatalf("Parse() error = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestWithClockMaxAge(t *testing.T) {
+	t.Parallel()
+	key := gojwe.MustGenerateKey()
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	j := gojwe.New(gojwe.XChaCha20, gojwe.WithClock(clock), gojwe.WithMaxAge(time.Hour), gojwe.WithLeeway(0))
+
+	token, _ := j.Generate(map[string]any{"iat": clock.Now().Unix()}, key)
+	clock.Advance(59 * time.Minute)
+	if _, err := j.Parse(token, key); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrTokenTooOld) {
+		t.Fatalf("Parse() error = %v, want ErrTokenTooOld", err)
+	}
+}
+
+func TestWithClockStores(t *testing.T) {
+	t.Parallel()
+	key := gojwe.MustGenerateKey()
+	clock := gojwe.NewFakeClock(time.Unix(1600000000, 0))
+	revoker := gojwe.NewMemoryRevoker()
+	j := gojwe.New(gojwe.XChaCha20, gojwe.WithClock(clock),
+		gojwe.WithReplayProtection(gojwe.NewMemoryReplayStore()), gojwe.WithRevoker(revoker))
+
+	// Entries expire on the instance clock, not the wall clock.
+	exp := clock.Now().Add(time.Hour)
+	token, _ := j.Generate(map[string]any{"jti": "a", "exp": exp.Unix()}, key)
+	if _, err := j.Parse(token, key); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrTokenReplayed) {
+		t.Fatalf("second Parse() error = %v, want ErrTokenReplayed", err)
+	}
+
+	revoker.Revoke("b", exp)
+	revoked, _ := j.Generate(map[string]any{"jti": "b", "exp": exp.Unix()}, key)
+	if _, err := j.Parse(revoked, key); !errors.Is(err, gojwe.ErrTokenRevoked) {
+		t.Fatalf("Parse() of a revoked token error = %v, want ErrTokenRevoked", err)
+	}
+}
@@ -0,0 +1,243 @@
+package gojwe
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/goccy/go-json"
+)
+
+// claimConstraint pins the claim name to one of the accepted values. err is
+// the sentinel reported on mismatch, next to ErrClaimMismatch.
+type claimConstraint struct {
+	name     string
+	accepted []any
+	err      error
+}
+
+// ClaimMismatchError reports that a claim pinned with WithSubject,
+// WithClaimEquals or WithClaimIn is absent or has another value; find it in the
+// returned *ValidationError with errors.As. It matches
+// ErrClaimMismatch with errors.Is, and ErrInvalidSubject for "sub" constraints
+// set with WithSubject.
+type ClaimMismatchError struct {
+	// Claim is the name of the offending claim.
+	Claim string
+
+	// Expected holds the accepted values.
+	Expected []any
+
+	// Actual is the value found in the token, or nil when the claim is absent.
+	Actual any
+
+	err error
+}
+
+func (e *ClaimMismatchError) Error() string {
+	if e.Actual == nil {
+		return fmt.Sprintf("gojwe: claim %q is missing, want one of %v", e.Claim, e.Expected)
+	}
+	return fmt.Sprintf("gojwe: claim %q is %v, want one of %v", e.Claim, e.Actual, e.Expected)
+}
+
+// Is reports whether target is ErrClaimMismatch or the more specific sentinel
+// of the constraint.
+func (e *ClaimMismatchError) Is(target error) bool {
+	return target == ErrClaimMismatch || (e.err != nil && target == e.err)
+}
+
+// checkConstraints enforces the claim constraints configured in opts.
+func checkConstraints(c *claimSet, opts options, v *validation) error {
+	for _, cc := range opts.constraints {
+		actual, err := c.get(cc.name)
+		if err != nil {
+			return err
+		}
+		if actual != nil && claimMatchesAny(actual, cc.accepted) {
+			continue
+		}
+		mismatch := &ClaimMismatchError{Claim: cc.name, Expected: cc.accepted, Actual: actual, err: cc.err}
+		if v.fail(&ClaimError{Claim: cc.name, Expected: cc.accepted, Actual: actual, Err: mismatch}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// get returns the value of the claim name, or nil when it is absent.
+// Registered string claims are read from c; other claims from the payload.
+func (c *claimSet) get(name string) (any, error) {
+	switch name {
+	case "iss":
+		return presentString(c.iss), nil
+	case "sub":
+		return presentString(c.sub), nil
+	case "jti":
+		return presentString(c.jti), nil
+	case "aud":
+		if !audiencePresent(c.aud) {
+			return nil, nil
+		}
+		return c.aud, nil
+	}
+	claims, err := c.exactPayload()
+	if err != nil {
+		return nil, err
+	}
+	return claims[name], nil
+}
+
+// presentString returns s, or nil when it is empty.
+func presentString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// claimMatchesAny reports whether the claim value actual matches one of the
+// accepted values.
+func claimMatchesAny(actual any, accepted []any) bool {
+	for _, want := range accepted {
+		if claimMatches(actual, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimMatches compares a claim value with an accepted value. Numbers compare
+// by value whatever their Go type. A string array accepted value matches an
+// array claim holding the same strings, each as often, in any order, while a
+// scalar accepted value matches an array claim that contains it, as with
+// "aud".
+func claimMatches(actual, want any) bool {
+	if list, ok := stringList(want); ok {
+		got, ok := stringList(actual)
+		return ok && sameStrings(got, list)
+	}
+
+	switch a := actual.(type) {
+	case []any:
+		for _, item := range a {
+			if scalarEqual(item, want) {
+				return true
+			}
+		}
+		return false
+	case []string, ClaimStrings:
+		list, _ := stringList(a)
+		for _, item := range list {
+			if scalarEqual(item, want) {
+				return true
+			}
+		}
+		return false
+	}
+	return scalarEqual(actual, want)
+}
+
+// sameStrings reports whether a and b hold the same strings with the same
+// multiplicity.
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// scalarEqual compares two strings, booleans or numbers. Integers compare
+// exactly; only non-integral numbers compare as float64.
+func scalarEqual(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return false
+		}
+		if i, ok := toInt64(a); ok {
+			j, ok := toInt64(b)
+			return ok && i == j
+		}
+		if _, ok := toInt64(b); ok {
+			return false
+		}
+		return x == y
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	return false
+}
+
+// toFloat converts any Go or JSON number to float64.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// stringList returns v as a list of strings when it is a string array.
+func stringList(v any) ([]string, bool) {
+	switch a := v.(type) {
+	case []string:
+		return a, true
+	case ClaimStrings:
+		return a, true
+	case []any:
+		out := make([]string, 0, len(a))
+		for _, item := range a {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
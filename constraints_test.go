@@ -0,0 +1,91 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestWithSubject(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithSubject("svc-billing"))
+
+		good, _ := j.Generate(map[string]any{"sub": "svc-billing"}, genKey)
+		bad, _ := j.Generate(map[string]any{"sub": "svc-other"}, genKey)
+		if _, err := j.Parse(good, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() error = %v", alg, err)
+		}
+
+		_, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, bad, parseKey)
+		if !errors.Is(err, gojwe.ErrInvalidSubject) || !errors.Is(err, gojwe.ErrClaimMismatch) {
+			t.Fatalf("[%s] ParseClaims() error = %v, want ErrInvalidSubject", alg, err)
+		}
+		var mismatch *gojwe.ClaimMismatchError
+		if !errors.As(err, &mismatch) || mismatch.Claim != "sub" || mismatch.Actual != "svc-other" {
+			t.Fatalf("[%s] ParseClaims() error = %#v, want a ClaimMismatchError for sub", alg, err)
+		}
+	}
+}
+
+func TestWithClaimEquals(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	tests := []struct {
+		name   string
+		opt    gojwe.Option
+		claims map[string]any
+		ok     bool
+	}{
+		{"string", gojwe.WithClaimEquals("token_use", "access"), map[string]any{"token_use": "access"}, true},
+		{"string mismatch", gojwe.WithClaimEquals("token_use", "access"), map[string]any{"token_use": "id"}, false},
+		{"missing", gojwe.WithClaimEquals("token_use", "access"), map[string]any{}, false},
+		{"number", gojwe.WithClaimEquals("level", 3), map[string]any{"level": 3}, true},
+		{"number mismatch", gojwe.WithClaimEquals("level", 3), map[string]any{"level": 4}, false},
+		{"number type", gojwe.WithClaimEquals("level", 3), map[string]any{"level": "3"}, false},
+		{"large integer", gojwe.WithClaimEquals("uid", int64(9007199254740993)), map[string]any{"uid": int64(9007199254740993)}, true},
+		{"large integer mismatch", gojwe.WithClaimEquals("uid", int64(9007199254740993)), map[string]any{"uid": int64(9007199254740992)}, false},
+		{"fraction", gojwe.WithClaimEquals("ratio", 1.5), map[string]any{"ratio": 1.5}, true},
+		{"fraction mismatch", gojwe.WithClaimEquals("ratio", 1), map[string]any{"ratio": 1.5}, false},
+		{"bool", gojwe.WithClaimEquals("mfa", true), map[string]any{"mfa": true}, true},
+		{"array contains", gojwe.WithClaimEquals("groups", "ops"), map[string]any{"groups": []string{"dev", "ops"}}, true},
+		{"array equals", gojwe.WithClaimEquals("amr", []string{"pwd", "otp"}), map[string]any{"amr": []string{"otp", "pwd"}}, true},
+		{"array differs", gojwe.WithClaimEquals("amr", []string{"pwd", "otp"}), map[string]any{"amr": []string{"pwd"}}, false},
+		{"array multiplicity", gojwe.WithClaimEquals("amr", []string{"pwd", "pwd", "otp"}), map[string]any{"amr": []string{"pwd", "otp", "otp"}}, false},
+		{"in", gojwe.WithClaimIn("tid", "tenant-a", "tenant-b"), map[string]any{"tid": "tenant-b"}, true},
+		{"not in", gojwe.WithClaimIn("tid", "tenant-a", "tenant-b"), map[string]any{"tid": "tenant-c"}, false},
+		{"aud", gojwe.WithClaimEquals("aud", "api"), map[string]any{"aud": []string{"api", "web"}}, true},
+		{"aud missing", gojwe.WithClaimEquals("aud", "api"), map[string]any{}, false},
+	}
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		for _, tt := range tests {
+			j := gojwe.New(alg, tt.opt)
+			token, _ := j.Generate(tt.claims, genKey)
+
+			_, err := j.Parse(token, parseKey)
+			if tt.ok != (err == nil) || (err != nil && !errors.Is(err, gojwe.ErrClaimMismatch)) {
+				t.Fatalf("[%s] %s: Parse() error = %v", alg, tt.name, err)
+			}
+			_, err = gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey)
+			if tt.ok != (err == nil) || (err != nil && !errors.Is(err, gojwe.ErrClaimMismatch)) {
+				t.Fatalf("[%s] %s: ParseClaims() error = %v", alg, tt.name, err)
+			}
+		}
+	}
+}
+
+func TestClaimConstraintsAccumulate(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20,
+		gojwe.WithClaimEquals("azp", "web-app"),
+		gojwe.WithClaimEquals("token_use", "access"),
+	)
+	token, _ := j.Generate(map[string]any{"azp": "web-app", "token_use": "id"}, key)
+
+	var mismatch *gojwe.ClaimMismatchError
+	if _, err := j.Parse(token, key); !errors.As(err, &mismatch) || mismatch.Claim != "token_use" {
+		t.Fatalf("Parse() error = %v, want a ClaimMismatchError for token_use", err)
+	}
+}
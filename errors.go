@@ -35,4 +35,68 @@ var (
 	// ErrInvalidIssuer is returned when the "iss" claim does not match the
 	// issuer configured with WithIssuer.
 	ErrInvalidIssuer = errors.New("gojwe: invalid issuer")
+
+	// ErrInvalidSubject is returned when the "sub" claim does not equal the
+	// subject configured with WithSubject. The error is a *ClaimMismatchError.
+	ErrInvalidSubject = errors.New("gojwe: invalid subject")
+
+	// ErrClaimMismatch is returned when a claim pinned with WithSubject,
+	// WithClaimEquals or WithClaimIn is absent or has another value. The error
+	// is a *ClaimMismatchError naming the claim.
+	ErrClaimMismatch = errors.New("gojwe: claim mismatch")
+
+	// ErrInsufficientScope is returned when the token lacks a scope or role
+	// required with WithRequiredScopes or WithRequiredRoles. The error names it.
+	ErrInsufficientScope = errors.New("gojwe: insufficient scope")
+
+	// ErrMissingClaim is returned when a claim required by the configured
+	// options is absent, e.g. "jti" with WithReplayProtection. The error names
+	// the claim.
+	ErrMissingClaim = errors.New("gojwe: missing required claim")
+
+	// ErrInvalidClaimType is returned by the MapClaims accessors when a claim
+	// does not have the requested type.
+	ErrInvalidClaimType = errors.New("gojwe: invalid claim type")
+
+	// ErrTokenTooOld is returned when the token is older, or was issued for
+	// longer, than the maximum age configured with WithMaxAge.
+	ErrTokenTooOld = errors.New("gojwe: token is too old")
+
+	// ErrTokenReplayed is returned by WithReplayProtection when a token's "jti"
+	// has already been accepted once.
+	ErrTokenReplayed = errors.New("gojwe: token has already been used")
+
+	// ErrTokenRevoked is returned when the Revoker configured with WithRevoker
+	// reports the token as revoked.
+	ErrTokenRevoked = errors.New("gojwe: token has been revoked")
+
+	// ErrSessionInvalidated is returned when the token was issued before the
+	// epoch of its subject in the SessionEpochStore configured with
+	// WithSessionEpochs.
+	ErrSessionInvalidated = errors.New("gojwe: session has been invalidated")
+
+	// ErrSessionExpired is returned by Refresh when the session has reached the
+	// maximum lifetime set with WithMaxSession.
+	ErrSessionExpired = errors.New("gojwe: session has reached its maximum lifetime")
+
+	// ErrInvalidTokenType is returned by TokenIssuer when an access token is
+	// presented as a refresh token or the other way round.
+	ErrInvalidTokenType = errors.New("gojwe: unexpected token type")
+
+	// ErrRefreshTokenReused is returned by TokenIssuer.Rotate when a refresh
+	// token that was already rotated is presented again. Its whole family is
+	// revoked.
+	ErrRefreshTokenReused = errors.New("gojwe: refresh token has already been used")
+
+	// ErrTokenFamilyRevoked is returned by TokenIssuer when the token belongs
+	// to a revoked family.
+	ErrTokenFamilyRevoked = errors.New("gojwe: token family has been revoked")
+
+	// ErrKeyUsageExceeded is returned by Generate when the key has reached the
+	// limit set with WithKeyUsageHardLimit and must be rotated.
+	ErrKeyUsageExceeded = errors.New("gojwe: key usage limit exceeded")
+
+	// ErrKeyAlgorithmMismatch is returned when a PreparedKey is used with a JWE
+	// of a different algorithm than the one it was prepared for.
+	ErrKeyAlgorithmMismatch = errors.New("gojwe: prepared key belongs to a different algorithm")
 )
@@ -0,0 +1,29 @@
+package gojwe
+
+import "strings"
+
+// Internals exported to the gojwe_test package.
+var (
+	HPKEDecap          = hpkeDecap
+	HPKEKeySchedule    = hpkeKeySchedule
+	HPKESetupSender    = hpkeSetupSender
+	HPKESetupRecipient = hpkeSetupRecipient
+)
+
+// HPKEDeriveKeyPair implements DeriveKeyPair of DHKEM(X25519, HKDF-SHA256)
+// (RFC 9180 §7.1.3), which the test vectors use to derive the ephemeral key.
+func HPKEDeriveKeyPair(ikm []byte) []byte {
+	prk := hpkeLabeledExtract(hpkeKemSuiteID, nil, "dkp_prk", ikm)
+	return hpkeLabeledExpand(hpkeKemSuiteID, prk, "sk", nil, 32)
+}
+
+// SignToken recomputes the HMAC signature of a ChaCha20 or XChaCha20 token
+// whose header or ciphertext was modified.
+func SignToken(alg string, key []byte, token string) string {
+	prepared, err := PrepareKey(alg, key)
+	if err != nil {
+		panic(err)
+	}
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + parts[1] + "." + prepared.sign(parts[0], parts[1])
+}
@@ -8,8 +8,20 @@ import (
 	"github.com/prongbang/gojwe"
 )
 
+// allAlgs lists every algorithm. Use keysFor for the keys to generate and
+// parse tokens with, since HPKE takes a key pair.
 func allAlgs() []string {
-	return []string{gojwe.AESGCM256, gojwe.ChaCha20, gojwe.XChaCha20}
+	return []string{gojwe.AESGCM256, gojwe.ChaCha20, gojwe.XChaCha20, gojwe.HPKE}
+}
+
+// keysFor returns the keys tokens of alg are generated and parsed with: key
+// for both with the symmetric algorithms, and the RFC 9180 test key pair with
+// HPKE.
+func keysFor(alg string, key []byte) (genKey, parseKey []byte) {
+	if alg == gojwe.HPKE {
+		return hpkePublicKey, hpkePrivateKey
+	}
+	return key, key
 }
 
 func TestGenerateKey(t *testing.T) {
@@ -53,19 +65,20 @@ func TestInvalidKeySize(t *testing.T) {
 func TestExpiredTokenIsRejected(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		j := gojwe.New(alg)
 		token, err := j.Generate(map[string]any{
 			"sub": "user-1",
 			"exp": time.Now().Add(-time.Hour).Unix(),
-		}, key)
+		}, genKey)
 		if err != nil {
 			t.Fatalf("[%s] Generate() error = %v", alg, err)
 		}
 
-		if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrTokenExpired) {
+		if _, err := j.Parse(token, parseKey); !errors.Is(err, gojwe.ErrTokenExpired) {
 			t.Fatalf("[%s] Parse() error = %v, want ErrTokenExpired", alg, err)
 		}
-		if j.Verify(token, key) {
+		if j.Verify(token, parseKey) {
 			t.Fatalf("[%s] Verify() = true for expired token, want false", alg)
 		}
 	}
@@ -74,11 +87,12 @@ func TestExpiredTokenIsRejected(t *testing.T) {
 func TestNotYetValidTokenIsRejected(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		j := gojwe.New(alg)
 		token, _ := j.Generate(map[string]any{
 			"nbf": time.Now().Add(time.Hour).Unix(),
-		}, key)
-		if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrTokenNotYetValid) {
+		}, genKey)
+		if _, err := j.Parse(token, parseKey); !errors.Is(err, gojwe.ErrTokenNotYetValid) {
 			t.Fatalf("[%s] Parse() error = %v, want ErrTokenNotYetValid", alg, err)
 		}
 	}
@@ -87,19 +101,20 @@ func TestNotYetValidTokenIsRejected(t *testing.T) {
 func TestValidTokenPasses(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		j := gojwe.New(alg)
 		token, _ := j.Generate(map[string]any{
 			"sub": "user-1",
 			"exp": time.Now().Add(time.Hour).Unix(),
-		}, key)
-		claims, err := j.Parse(token, key)
+		}, genKey)
+		claims, err := j.Parse(token, parseKey)
 		if err != nil {
 			t.Fatalf("[%s] Parse() error = %v", alg, err)
 		}
 		if claims["sub"] != "user-1" {
 			t.Fatalf("[%s] sub = %v, want user-1", alg, claims["sub"])
 		}
-		if !j.Verify(token, key) {
+		if !j.Verify(token, parseKey) {
 			t.Fatalf("[%s] Verify() = false, want true", alg)
 		}
 	}
@@ -108,11 +123,12 @@ func TestValidTokenPasses(t *testing.T) {
 func TestWithoutTimeValidation(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		gen := gojwe.New(alg)
-		token, _ := gen.Generate(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, key)
+		token, _ := gen.Generate(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, genKey)
 
 		j := gojwe.New(alg, gojwe.WithoutTimeValidation())
-		if _, err := j.Parse(token, key); err != nil {
+		if _, err := j.Parse(token, parseKey); err != nil {
 			t.Fatalf("[%s] Parse() with WithoutTimeValidation error = %v, want nil", alg, err)
 		}
 	}
@@ -122,11 +138,12 @@ func TestWithLeeway(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	// Token expired 10s ago; a 30s leeway should still accept it.
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		gen := gojwe.New(alg)
-		token, _ := gen.Generate(map[string]any{"exp": time.Now().Add(-10 * time.Second).Unix()}, key)
+		token, _ := gen.Generate(map[string]any{"exp": time.Now().Add(-10 * time.Second).Unix()}, genKey)
 
 		j := gojwe.New(alg, gojwe.WithLeeway(30*time.Second))
-		if _, err := j.Parse(token, key); err != nil {
+		if _, err := j.Parse(token, parseKey); err != nil {
 			t.Fatalf("[%s] Parse() with 30s leeway error = %v, want nil", alg, err)
 		}
 	}
@@ -156,21 +173,22 @@ func TestTamperedTokenIsRejected(t *testing.T) {
 func TestAudienceValidation(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		gen := gojwe.New(alg)
 		token, _ := gen.Generate(map[string]any{
 			"aud": []any{"api", "web"},
 			"exp": time.Now().Add(time.Hour).Unix(),
-		}, key)
+		}, genKey)
 
 		// Correct audience passes.
 		ok := gojwe.New(alg, gojwe.WithAudience("web"))
-		if _, err := ok.Parse(token, key); err != nil {
+		if _, err := ok.Parse(token, parseKey); err != nil {
 			t.Fatalf("[%s] Parse() with matching audience error = %v", alg, err)
 		}
 
 		// Wrong audience is rejected.
 		bad := gojwe.New(alg, gojwe.WithAudience("mobile"))
-		if _, err := bad.Parse(token, key); !errors.Is(err, gojwe.ErrInvalidAudience) {
+		if _, err := bad.Parse(token, parseKey); !errors.Is(err, gojwe.ErrInvalidAudience) {
 			t.Fatalf("[%s] Parse() error = %v, want ErrInvalidAudience", alg, err)
 		}
 	}
@@ -179,19 +197,20 @@ func TestAudienceValidation(t *testing.T) {
 func TestIssuerValidation(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		gen := gojwe.New(alg)
 		token, _ := gen.Generate(map[string]any{
 			"iss": "auth.example.com",
 			"exp": time.Now().Add(time.Hour).Unix(),
-		}, key)
+		}, genKey)
 
 		ok := gojwe.New(alg, gojwe.WithIssuer("auth.example.com"))
-		if _, err := ok.Parse(token, key); err != nil {
+		if _, err := ok.Parse(token, parseKey); err != nil {
 			t.Fatalf("[%s] Parse() with matching issuer error = %v", alg, err)
 		}
 
 		bad := gojwe.New(alg, gojwe.WithIssuer("evil.example.com"))
-		if _, err := bad.Parse(token, key); !errors.Is(err, gojwe.ErrInvalidIssuer) {
+		if _, err := bad.Parse(token, parseKey); !errors.Is(err, gojwe.ErrInvalidIssuer) {
 			t.Fatalf("[%s] Parse() error = %v, want ErrInvalidIssuer", alg, err)
 		}
 	}
@@ -200,19 +219,20 @@ func TestIssuerValidation(t *testing.T) {
 func TestIssuedAtValidation(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		gen := gojwe.New(alg)
 		token, _ := gen.Generate(map[string]any{
 			"iat": time.Now().Add(time.Hour).Unix(), // issued in the future
-		}, key)
+		}, genKey)
 
 		// Off by default: future iat is tolerated.
-		if _, err := gojwe.New(alg).Parse(token, key); err != nil {
+		if _, err := gojwe.New(alg).Parse(token, parseKey); err != nil {
 			t.Fatalf("[%s] Parse() without iat validation error = %v", alg, err)
 		}
 
 		// Opt-in: future iat is rejected.
 		strict := gojwe.New(alg, gojwe.WithIssuedAtValidation())
-		if _, err := strict.Parse(token, key); !errors.Is(err, gojwe.ErrTokenUsedBeforeIssued) {
+		if _, err := strict.Parse(token, parseKey); !errors.Is(err, gojwe.ErrTokenUsedBeforeIssued) {
 			t.Fatalf("[%s] Parse() error = %v, want ErrTokenUsedBeforeIssued", alg, err)
 		}
 	}
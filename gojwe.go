@@ -4,6 +4,7 @@ const (
 	AESGCM256 = "AES-GCM-256"
 	ChaCha20  = "ChaCha20"
 	XChaCha20 = "XChaCha20"
+	HPKE      = "HPKE"
 )
 
 type Header struct {
@@ -11,6 +12,7 @@ type Header struct {
 	Enc string `json:"enc"`
 	Iv  string `json:"iv"`
 	Tag string `json:"tag"`
+	Ek  string `json:"ek,omitempty"`
 }
 
 type Serialize struct {
@@ -40,6 +42,8 @@ func New(alg string, opts ...Option) JWE {
 		return &JweChaCha20{opts: o}
 	case XChaCha20:
 		return &JweXChaCha20{opts: o}
+	case HPKE:
+		return &JweHPKE{opts: o}
 	}
 	return nil
 }
@@ -0,0 +1,184 @@
+package gojwe
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// hpkeModeBase is the RFC 9180 mode_base identifier; the PSK and Auth modes
+// are not supported.
+const hpkeModeBase = 0x00
+
+// HPKE (RFC 9180) labels for the single cipher suite supported by this package:
+// DHKEM(X25519, HKDF-SHA256), HKDF-SHA256 and ChaCha20-Poly1305.
+var (
+	hpkeVersion = []byte("HPKE-v1")
+
+	// hpkeKemSuiteID is "KEM" || I2OSP(kem_id, 2) with kem_id 0x0020
+	// (DHKEM(X25519, HKDF-SHA256)), used by the KEM functions.
+	hpkeKemSuiteID = []byte{'K', 'E', 'M', 0x00, 0x20}
+
+	// hpkeSuiteID is "HPKE" || I2OSP(kem_id, 2) || I2OSP(kdf_id, 2) || I2OSP(aead_id, 2)
+	// with HKDF-SHA256 (0x0001) and ChaCha20-Poly1305 (0x0003), used by the key
+	// schedule.
+	hpkeSuiteID = []byte{'H', 'P', 'K', 'E', 0x00, 0x20, 0x00, 0x01, 0x00, 0x03}
+)
+
+// hpkeLabeledExtract implements LabeledExtract from RFC 9180 §4.
+func hpkeLabeledExtract(suiteID, salt []byte, label string, ikm []byte) []byte {
+	labeled := make([]byte, 0, len(hpkeVersion)+len(suiteID)+len(label)+len(ikm))
+	labeled = append(labeled, hpkeVersion...)
+	labeled = append(labeled, suiteID...)
+	labeled = append(labeled, label...)
+	labeled = append(labeled, ikm...)
+	return hkdf.Extract(sha256.New, labeled, salt)
+}
+
+// hpkeLabeledExpand implements LabeledExpand from RFC 9180 §4.
+func hpkeLabeledExpand(suiteID, prk []byte, label string, info []byte, length int) []byte {
+	labeled := make([]byte, 2, 2+len(hpkeVersion)+len(suiteID)+len(label)+len(info))
+	binary.BigEndian.PutUint16(labeled, uint16(length))
+	labeled = append(labeled, hpkeVersion...)
+	labeled = append(labeled, suiteID...)
+	labeled = append(labeled, label...)
+	labeled = append(labeled, info...)
+
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, labeled), out); err != nil {
+		// Only reachable when length exceeds 255*Nh, which never happens here.
+		panic(err)
+	}
+	return out
+}
+
+// hpkeExtractAndExpand derives the KEM shared secret from the Diffie-Hellman
+// output and the KEM context (enc || pkR).
+func hpkeExtractAndExpand(dh, kemContext []byte) []byte {
+	eaePrk := hpkeLabeledExtract(hpkeKemSuiteID, nil, "eae_prk", dh)
+	return hpkeLabeledExpand(hpkeKemSuiteID, eaePrk, "shared_secret", kemContext, 32)
+}
+
+// hpkeEncap generates an ephemeral key pair and returns the shared secret and
+// the encapsulated key to send to the owner of pkR.
+func hpkeEncap(r io.Reader, pkR []byte) (sharedSecret, enc []byte, err error) {
+	skE, pkE, err := generateHPKEKeyPair(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh, err := curve25519.X25519(skE, pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	kemContext := make([]byte, 0, len(pkE)+len(pkR))
+	kemContext = append(kemContext, pkE...)
+	kemContext = append(kemContext, pkR...)
+	return hpkeExtractAndExpand(dh, kemContext), pkE, nil
+}
+
+// hpkeDecap recovers the shared secret from the encapsulated key using the
+// recipient private key skR.
+func hpkeDecap(enc, skR []byte) ([]byte, error) {
+	dh, err := curve25519.X25519(skR, enc)
+	if err != nil {
+		return nil, err
+	}
+	pkR, err := curve25519.X25519(skR, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := make([]byte, 0, len(enc)+len(pkR))
+	kemContext = append(kemContext, enc...)
+	kemContext = append(kemContext, pkR...)
+	return hpkeExtractAndExpand(dh, kemContext), nil
+}
+
+// hpkeKeySchedule runs the base-mode key schedule (RFC 9180 §5.1) and returns
+// the AEAD key and base nonce for the context.
+func hpkeKeySchedule(sharedSecret, info []byte) (key, baseNonce []byte) {
+	pskIDHash := hpkeLabeledExtract(hpkeSuiteID, nil, "psk_id_hash", nil)
+	infoHash := hpkeLabeledExtract(hpkeSuiteID, nil, "info_hash", info)
+
+	ctx := make([]byte, 0, 1+len(pskIDHash)+len(infoHash))
+	ctx = append(ctx, hpkeModeBase)
+	ctx = append(ctx, pskIDHash...)
+	ctx = append(ctx, infoHash...)
+
+	secret := hpkeLabeledExtract(hpkeSuiteID, sharedSecret, "secret", nil)
+	key = hpkeLabeledExpand(hpkeSuiteID, secret, "key", ctx, chacha20poly1305.KeySize)
+	baseNonce = hpkeLabeledExpand(hpkeSuiteID, secret, "base_nonce", ctx, chacha20poly1305.NonceSize)
+	return key, baseNonce
+}
+
+// hpkeSetupSender implements SetupBaseS (RFC 9180 §5.1.1): it encapsulates a
+// fresh shared secret to pkR and returns the encapsulated key together with the
+// AEAD and base nonce of the context. Only one message is sealed per context, so
+// the sequence number is always zero and the nonce is the base nonce.
+func hpkeSetupSender(r io.Reader, pkR, info []byte) (enc []byte, aead cipher.AEAD, nonce []byte, err error) {
+	sharedSecret, enc, err := hpkeEncap(r, pkR)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	key, nonce := hpkeKeySchedule(sharedSecret, info)
+	aead, err = chacha20poly1305.New(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return enc, aead, nonce, nil
+}
+
+// hpkeSetupRecipient implements SetupBaseR (RFC 9180 §5.1.1), the inverse of
+// hpkeSetupSender using the recipient private key skR.
+func hpkeSetupRecipient(enc, skR, info []byte) (aead cipher.AEAD, nonce []byte, err error) {
+	sharedSecret, err := hpkeDecap(enc, skR)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, nonce := hpkeKeySchedule(sharedSecret, info)
+	aead, err = chacha20poly1305.New(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, nonce, nil
+}
+
+// generateHPKEKeyPair draws a random X25519 private key from r and returns it
+// together with its public key.
+func generateHPKEKeyPair(r io.Reader) (privateKey, publicKey []byte, err error) {
+	privateKey = make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(r, privateKey); err != nil {
+		return nil, nil, err
+	}
+	publicKey, err = curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKey, publicKey, nil
+}
+
+// GenerateHPKEKeyPair returns a new X25519 key pair for the HPKE algorithm. The
+// public key is passed to Generate and the private key to Parse/Verify.
+func GenerateHPKEKeyPair() (privateKey, publicKey []byte, err error) {
+	return generateHPKEKeyPair(rand.Reader)
+}
+
+// GenerateHPKEKeyPairFromReader is like GenerateHPKEKeyPair but reads the
+// private key from r. It is meant for reproducible test vectors together with
+// WithRand; production code should use GenerateHPKEKeyPair.
+func GenerateHPKEKeyPairFromReader(r io.Reader) (privateKey, publicKey []byte, err error) {
+	return generateHPKEKeyPair(r)
+}
+
+// HPKEPublicKey returns the X25519 public key belonging to an HPKE private key.
+func HPKEPublicKey(privateKey []byte) ([]byte, error) {
+	if err := validateKey(privateKey); err != nil {
+		return nil, err
+	}
+	return curve25519.X25519(privateKey, curve25519.Basepoint)
+}
@@ -14,6 +14,18 @@ type rawCodec interface {
 	getOptions() options
 }
 
+// preparedCodec is implemented by the built-in JWE algorithms to encrypt and
+// decrypt with a PreparedKey. Their rawCodec methods prepare a throwaway key
+// and delegate here, so both paths share one implementation.
+type preparedCodec interface {
+	rawCodec
+	// generatePrepared encrypts already-marshalled JSON payload bytes.
+	generatePrepared(payload []byte, key *PreparedKey) (string, error)
+	// decryptPrepared verifies and decrypts a token, returning the raw JSON
+	// payload bytes without validating claims.
+	decryptPrepared(token string, key *PreparedKey) ([]byte, error)
+}
+
 // claimsAccessor is satisfied by RegisteredClaims (and anything embedding it),
 // letting ParseClaims validate the registered claims straight from the parsed
 // struct without a second unmarshal.
@@ -22,5 +34,7 @@ type claimsAccessor interface {
 	GetNotBefore() (*NumericDate, error)
 	GetIssuedAt() (*NumericDate, error)
 	GetIssuer() (string, error)
+	GetSubject() (string, error)
 	GetAudience() (ClaimStrings, error)
+	GetID() (string, error)
 }
@@ -0,0 +1,131 @@
+package gojwe
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// issuance holds the options that fill in registered claims when a token is
+// generated.
+type issuance struct {
+	ttl       time.Duration
+	issuedAt  bool
+	notBefore bool
+	jti       func(now time.Time, r io.Reader) (string, error)
+	issuer    string
+	audience  []string
+}
+
+// active reports whether any issuance option is set.
+func (i issuance) active() bool {
+	return i.ttl > 0 || i.issuedAt || i.notBefore || i.jti != nil || i.issuer != "" || len(i.audience) > 0
+}
+
+// errPayloadNotObject is returned when issuance options meet a payload that is
+// not a JSON object, such as a bare string.
+var errPayloadNotObject = errors.New("gojwe: issuance options need a JSON object payload")
+
+// issue adds the registered claims configured with the issuance options to a
+// JSON payload, leaving claims that are already set (and not null) alone. The
+// payload is returned unchanged when no issuance option is set.
+func (o options) issue(payload []byte) ([]byte, error) {
+	if !o.issuance.active() {
+		return payload, nil
+	}
+
+	var claims map[string]any
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber() // keep the caller's numbers exactly as they were
+	if err := dec.Decode(&claims); err != nil || claims == nil {
+		return nil, errPayloadNotObject
+	}
+	missing := func(name string) bool {
+		v, ok := claims[name]
+		return !ok || v == nil
+	}
+
+	now := o.now()
+	is := o.issuance
+	if is.issuedAt && missing("iat") {
+		claims["iat"] = o.numericDate(now)
+	}
+	if is.notBefore && missing("nbf") {
+		claims["nbf"] = o.numericDate(now)
+	}
+	if is.ttl > 0 && missing("exp") {
+		claims["exp"] = o.numericDate(now.Add(is.ttl))
+	}
+	if is.jti != nil && missing("jti") {
+		id, err := is.jti(now, o.randReader())
+		if err != nil {
+			return nil, err
+		}
+		claims["jti"] = id
+	}
+	if is.issuer != "" && missing("iss") {
+		claims["iss"] = is.issuer
+	}
+	if len(is.audience) > 0 && missing("aud") {
+		claims["aud"] = ClaimStrings(is.audience)
+	}
+	return json.Marshal(claims)
+}
+
+// UUIDv4 returns a random (version 4) UUID read from r, for use with WithJTI.
+// now is unused. A nil r reads from crypto/rand.
+func UUIDv4(now time.Time, r io.Reader) (string, error) {
+	var u [16]byte
+	if _, err := io.ReadFull(orRand(r), u[:]); err != nil {
+		return "", err
+	}
+	return formatUUID(u, 4), nil
+}
+
+// UUIDv7 returns a time-ordered (version 7) UUID, for use with WithJTI. Its
+// leading 48 bits are now as Unix milliseconds, which keeps identifiers of
+// consecutive tokens close together in indexes; the rest is read from r. A nil
+// r reads from crypto/rand.
+func UUIDv7(now time.Time, r io.Reader) (string, error) {
+	var u [16]byte
+	if _, err := io.ReadFull(orRand(r), u[6:]); err != nil {
+		return "", err
+	}
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(now.UnixMilli()))
+	copy(u[:6], ms[2:])
+	return formatUUID(u, 7), nil
+}
+
+// orRand returns r, or crypto/rand when r is nil.
+func orRand(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+	return r
+}
+
+// formatUUID sets the version and RFC 9562 variant bits of u and formats it in
+// the canonical 8-4-4-4-12 form.
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
@@ -0,0 +1,156 @@
+package gojwe_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestIssuanceOptions(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg,
+			gojwe.WithClock(clock),
+			gojwe.WithTTL(time.Hour),
+			gojwe.WithIssuedAtNow(),
+			gojwe.WithNotBeforeNow(),
+			gojwe.WithJTI(gojwe.UUIDv4),
+			gojwe.WithDefaultIssuer("auth"),
+			gojwe.WithDefaultAudience("api"),
+		)
+
+		payload := map[string]any{"sub": "user-1"}
+		token, err := j.Generate(payload, genKey)
+		if err != nil {
+			t.Fatalf("[%s] Generate() error = %v", alg, err)
+		}
+		if len(payload) != 1 {
+			t.Fatalf("[%s] Generate() modified the payload: %v", alg, payload)
+		}
+
+		claims, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey)
+		if err != nil {
+			t.Fatalf("[%s] ParseClaims() error = %v", alg, err)
+		}
+		now := clock.Now()
+		if !claims.IssuedAt.Equal(now) || !claims.NotBefore.Equal(now) || !claims.ExpiresAt.Equal(now.Add(time.Hour)) {
+			t.Fatalf("[%s] times = iat %v nbf %v exp %v", alg, claims.IssuedAt, claims.NotBefore, claims.ExpiresAt)
+		}
+		if claims.Issuer != "auth" || len(claims.Audience) != 1 || claims.Audience[0] != "api" || claims.Subject != "user-1" {
+			t.Fatalf("[%s] claims = %+v", alg, claims)
+		}
+		if claims.ID == "" {
+			t.Fatalf("[%s] jti not set", alg)
+		}
+	}
+}
+
+func TestIssuanceKeepsExplicitClaims(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.XChaCha20,
+		gojwe.WithClock(clock),
+		gojwe.WithTTL(time.Hour),
+		gojwe.WithJTI(gojwe.UUIDv7),
+		gojwe.WithDefaultIssuer("auth"),
+		gojwe.WithDefaultAudience("api", "web"),
+	)
+
+	type claims struct {
+		gojwe.RegisteredClaims
+		Big int64 `json:"big"`
+	}
+	exp := gojwe.NewNumericDate(clock.Now().Add(5 * time.Minute))
+	token, err := gojwe.GenerateClaims(j, claims{
+		RegisteredClaims: gojwe.RegisteredClaims{Issuer: "other", ExpiresAt: exp, ID: "fixed"},
+		Big:              9007199254740993, // not representable as float64
+	}, key)
+	if err != nil {
+		t.Fatalf("GenerateClaims() error = %v", err)
+	}
+
+	got, err := gojwe.ParseClaims[claims](j, token, key)
+	if err != nil {
+		t.Fatalf("ParseClaims() error = %v", err)
+	}
+	if got.Issuer != "other" || got.ID != "fixed" || !got.ExpiresAt.Equal(exp.Time) {
+		t.Fatalf("explicit claims overwritten: %+v", got.RegisteredClaims)
+	}
+	if len(got.Audience) != 2 || got.Big != 9007199254740993 {
+		t.Fatalf("claims = %+v", got)
+	}
+}
+
+func TestIssuanceAppendGenerate(t *testing.T) {
+	raw := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithJTI(gojwe.UUIDv4))
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, raw)
+
+	token, err := gojwe.AppendGenerate(j, nil, []byte(`{"sub":"x"}`), key)
+	if err != nil {
+		t.Fatalf("AppendGenerate() error = %v", err)
+	}
+	claims, err := j.Parse(string(token), raw)
+	if err != nil || claims["jti"] == nil {
+		t.Fatalf("Parse() = %v, %v, want a jti", claims, err)
+	}
+}
+
+func TestIssuanceNonObjectPayload(t *testing.T) {
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithTTL(time.Hour))
+	if _, err := gojwe.GenerateClaims(j, "not an object", gojwe.MustGenerateKey()); err == nil {
+		t.Fatal("GenerateClaims() with a string payload succeeded, want error")
+	}
+}
+
+func TestUUIDs(t *testing.T) {
+	v4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	v7 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	now := time.Now()
+	a, _ := gojwe.UUIDv4(now, nil)
+	b, _ := gojwe.UUIDv4(now, nil)
+	if !v4.MatchString(a) || a == b {
+		t.Fatalf("UUIDv4() = %q, %q", a, b)
+	}
+
+	first, _ := gojwe.UUIDv7(now, nil)
+	second, _ := gojwe.UUIDv7(now.Add(2*time.Millisecond), nil)
+	if !v7.MatchString(first) || !v7.MatchString(second) {
+		t.Fatalf("UUIDv7() = %q, %q", first, second)
+	}
+	if first[:13] >= second[:13] {
+		t.Fatalf("UUIDv7() not time-ordered: %q then %q", first, second)
+	}
+
+	// Both take the time and random bytes they are given.
+	at := time.UnixMilli(0x018bcfe56800)
+	if got, _ := gojwe.UUIDv7(at, &countingReader{}); got != "018bcfe5-6800-7001-8203-040506070809" {
+		t.Fatalf("UUIDv7() = %q", got)
+	}
+	if got, _ := gojwe.UUIDv4(at, &countingReader{}); got != "00010203-0405-4607-8809-0a0b0c0d0e0f" {
+		t.Fatalf("UUIDv4() = %q", got)
+	}
+}
+
+func TestWithJTIUsesClockAndRand(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	clock := gojwe.NewFakeClock(time.UnixMilli(0x018bcfe56800))
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(clock), gojwe.WithRand(&countingReader{}), gojwe.WithJTI(gojwe.UUIDv7))
+
+	token, err := j.Generate(map[string]any{"sub": "x"}, key)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	claims, err := j.Parse(token, key)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if jti, _ := claims["jti"].(string); jti != "018bcfe5-6800-7001-8203-040506070809" {
+		t.Fatalf("jti = %q, want the clock time and the first random bytes", jti)
+	}
+}
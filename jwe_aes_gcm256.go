@@ -1,6 +1,13 @@
 package gojwe
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
 	"github.com/goccy/go-json"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwe"
@@ -21,9 +28,33 @@ func (j *JweAesGcm256) Generate(payload map[string]any, key []byte) (string, err
 
 // generate encrypts already-marshalled JSON payload bytes into a token.
 func (j *JweAesGcm256) generate(payloadByte []byte, key []byte) (string, error) {
-	if err := validateKey(key); err != nil {
+	prepared, err := newPreparedKey(AESGCM256, key)
+	if err != nil {
+		return "", err
+	}
+	return j.generatePrepared(payloadByte, prepared)
+}
+
+// generatePrepared encrypts already-marshalled JSON payload bytes with a
+// PreparedKey.
+func (j *JweAesGcm256) generatePrepared(payloadByte []byte, prepared *PreparedKey) (string, error) {
+	if err := prepared.forAlg(AESGCM256); err != nil {
+		return "", err
+	}
+	payloadByte, err := j.opts.issue(payloadByte)
+	if err != nil {
+		return "", err
+	}
+	if err := j.opts.recordUsage(prepared); err != nil {
 		return "", err
 	}
+	key := prepared.key
+
+	// jwx always draws from crypto/rand, so an injected reader needs the
+	// package's own encoder.
+	if j.opts.rand != nil {
+		return encryptA256GCMKW(j.opts.rand, payloadByte, key)
+	}
 
 	encrypted, err := jwe.Encrypt(payloadByte, jwe.WithKey(jwa.A256GCMKW, key))
 	if err != nil {
@@ -33,6 +64,55 @@ func (j *JweAesGcm256) generate(payloadByte []byte, key []byte) (string, error)
 	return string(encrypted), nil
 }
 
+// encryptA256GCMKW produces the same RFC 7516 compact serialization as
+// jwe.Encrypt with A256GCMKW + A256GCM, drawing the content-encryption key, the
+// key-wrap IV and the content IV (in that order) from r. It backs WithRand.
+func encryptA256GCMKW(r io.Reader, payload, key []byte) (string, error) {
+	random := make([]byte, KeySize+2*aesGcmNonceSize)
+	if _, err := io.ReadFull(r, random); err != nil {
+		return "", err
+	}
+	cek, kwIv, iv := random[:KeySize], random[KeySize:KeySize+aesGcmNonceSize], random[KeySize+aesGcmNonceSize:]
+
+	// Wrap the content-encryption key with AES-GCM under the caller's key
+	kw, err := newAesGcm(key)
+	if err != nil {
+		return "", err
+	}
+	wrapped := kw.Seal(nil, kwIv, cek, nil)
+	encryptedKey, kwTag := wrapped[:KeySize], wrapped[KeySize:]
+
+	// The protected header carries the key-wrap IV and tag (RFC 7518 §4.7.1)
+	header := `{"alg":"A256GCMKW","enc":"A256GCM","iv":"` + base64.RawURLEncoding.EncodeToString(kwIv) +
+		`","tag":"` + base64.RawURLEncoding.EncodeToString(kwTag) + `"}`
+	headerB64 := base64.RawURLEncoding.EncodeToString([]byte(header))
+
+	// Encrypt the payload with the content-encryption key, authenticating the header
+	content, err := newAesGcm(cek)
+	if err != nil {
+		return "", err
+	}
+	sealed := content.Seal(nil, iv, payload, []byte(headerB64))
+	tagStart := len(sealed) - content.Overhead()
+
+	return headerB64 + "." +
+		base64.RawURLEncoding.EncodeToString(encryptedKey) + "." +
+		base64.RawURLEncoding.EncodeToString(iv) + "." +
+		base64.RawURLEncoding.EncodeToString(sealed[:tagStart]) + "." +
+		base64.RawURLEncoding.EncodeToString(sealed[tagStart:]), nil
+}
+
+// aesGcmNonceSize is the standard 96-bit AES-GCM IV length.
+const aesGcmNonceSize = 12
+
+func newAesGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 func (j *JweAesGcm256) Verify(token string, key []byte) bool {
 	claims, err := j.Parse(token, key)
 
@@ -48,11 +128,11 @@ func (j *JweAesGcm256) Parse(token string, key []byte) (map[string]any, error) {
 	// Parse the decrypted payload
 	claims := map[string]any{}
 	if err = json.Unmarshal(decrypted, &claims); err != nil {
-		return nil, err
+		return nil, payloadError(err)
 	}
 
 	// Validate the registered claims (exp/nbf/iat/iss/aud)
-	if err = validateClaims(claims, j.opts); err != nil {
+	if err = validateClaims(claims, decrypted, token, j.opts); err != nil {
 		return nil, err
 	}
 
@@ -61,13 +141,101 @@ func (j *JweAesGcm256) Parse(token string, key []byte) (map[string]any, error) {
 
 // decrypt verifies and decrypts a token, returning the raw JSON payload bytes.
 func (j *JweAesGcm256) decrypt(token string, key []byte) ([]byte, error) {
-	if err := validateKey(key); err != nil {
+	prepared, err := newPreparedKey(AESGCM256, key)
+	if err != nil {
+		return nil, err
+	}
+	return j.decryptPrepared(token, prepared)
+}
+
+// decryptPrepared verifies and decrypts a token with a PreparedKey.
+func (j *JweAesGcm256) decryptPrepared(token string, prepared *PreparedKey) ([]byte, error) {
+	if err := prepared.forAlg(AESGCM256); err != nil {
 		return nil, err
 	}
 	if len(token) > MaxTokenBytes {
-		return nil, ErrInvalidToken
+		return nil, sizeError(len(token))
+	}
+	if j.opts.strict {
+		if err := j.checkStrict(token); err != nil {
+			return nil, err
+		}
+	}
+	payload, err := jwe.Decrypt([]byte(token), jwe.WithKey(jwa.A256GCMKW, prepared.key))
+	if err != nil {
+		return nil, classifyA256GCMKWError(token, err)
+	}
+	if err := j.opts.checkPayload(payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// checkStrict applies WithStrictDecoding to the header and the base64url
+// segments of a token before jwx decodes it leniently.
+func (j *JweAesGcm256) checkStrict(token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return tokenError(StageSize, ErrInvalidToken, segmentsError(len(parts), 5))
+	}
+	headerJSON, err := strictBase64.DecodeString(parts[0])
+	if err != nil {
+		return tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	var header Header
+	if err := j.opts.decodeHeader(headerJSON, &header); err != nil {
+		return tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	if _, err := strictBase64.DecodeString(header.Iv); err != nil {
+		return tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	if _, err := strictBase64.DecodeString(header.Tag); err != nil {
+		return tokenError(StageSignature, ErrInvalidToken, err)
+	}
+	for i, part := range parts[1:] {
+		if _, err := strictBase64.DecodeString(part); err != nil {
+			stage := StageDecrypt
+			if i == 3 {
+				stage = StageSignature
+			}
+			return tokenError(stage, ErrInvalidToken, err)
+		}
+	}
+	return nil
+}
+
+// classifyA256GCMKWError turns a jwx decryption error into a *TokenError. jwx
+// does not say which step failed, so the compact serialization is inspected
+// again; a well-formed token that still fails to decrypt was made with another
+// key or tampered with.
+func classifyA256GCMKWError(token string, err error) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return tokenError(StageSize, ErrInvalidToken, segmentsError(len(parts), 5))
+	}
+
+	headerJSON, herr := base64.RawURLEncoding.DecodeString(parts[0])
+	if herr != nil {
+		return tokenError(StageHeader, ErrInvalidToken, herr)
+	}
+	var header Header
+	if herr := json.Unmarshal(headerJSON, &header); herr != nil {
+		return tokenError(StageHeader, ErrInvalidToken, herr)
+	}
+	if header.Alg != string(jwa.A256GCMKW) || header.Enc != string(jwa.A256GCM) {
+		return tokenError(StageHeader, ErrInvalidToken, fmt.Errorf("unexpected alg %q and enc %q", header.Alg, header.Enc))
+	}
+
+	// encrypted key, iv and ciphertext, then the authentication tag
+	for _, part := range parts[1:4] {
+		if _, derr := base64.RawURLEncoding.DecodeString(part); derr != nil {
+			return tokenError(StageDecrypt, ErrInvalidToken, derr)
+		}
+	}
+	if _, derr := base64.RawURLEncoding.DecodeString(parts[4]); derr != nil {
+		return tokenError(StageSignature, ErrInvalidToken, derr)
 	}
-	return jwe.Decrypt([]byte(token), jwe.WithKey(jwa.A256GCMKW, key))
+	return tokenError(StageSignature, ErrInvalidSignature, err)
 }
 
 func (j *JweAesGcm256) getOptions() options { return j.opts }
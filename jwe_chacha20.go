@@ -1,11 +1,12 @@
 package gojwe
 
 import (
+	"crypto/cipher"
 	"crypto/hmac"
-	"crypto/rand"
 	"encoding/base64"
 	"github.com/goccy/go-json"
 	"golang.org/x/crypto/chacha20poly1305"
+	"io"
 	"strings"
 )
 
@@ -13,15 +14,10 @@ type JweChaCha20 struct {
 	opts options
 }
 
-func (j *JweChaCha20) encrypt(payload []byte, key []byte) (*Serialize, error) {
-	aead, err := chacha20poly1305.New(key)
-	if err != nil {
-		return nil, err
-	}
-
+func (j *JweChaCha20) encrypt(payload []byte, aead cipher.AEAD) (*Serialize, error) {
 	// Generate a 12-byte nonce (IV) for ChaCha20-Poly1305
 	nonce := make([]byte, chacha20poly1305.NonceSize)
-	_, err = rand.Read(nonce)
+	_, err := io.ReadFull(j.opts.randReader(), nonce)
 	if err != nil {
 		return nil, err
 	}
@@ -52,15 +48,30 @@ func (j *JweChaCha20) Generate(payload map[string]any, key []byte) (string, erro
 
 // generate encrypts already-marshalled JSON payload bytes into a token.
 func (j *JweChaCha20) generate(payloadByte []byte, key []byte) (string, error) {
-	if err := validateKey(key); err != nil {
+	// Derive independent encryption and MAC keys (key separation)
+	prepared, err := newPreparedKey(ChaCha20, key)
+	if err != nil {
 		return "", err
 	}
+	return j.generatePrepared(payloadByte, prepared)
+}
 
-	// Derive independent encryption and MAC keys (key separation)
-	encKey, macKey := deriveKeys(key)
+// generatePrepared encrypts already-marshalled JSON payload bytes with the
+// derived keys held by a PreparedKey.
+func (j *JweChaCha20) generatePrepared(payloadByte []byte, key *PreparedKey) (string, error) {
+	if err := key.forAlg(ChaCha20); err != nil {
+		return "", err
+	}
+	payloadByte, err := j.opts.issue(payloadByte)
+	if err != nil {
+		return "", err
+	}
+	if err := j.opts.recordUsage(key); err != nil {
+		return "", err
+	}
 
 	// Encrypt payload
-	serialize, err := j.encrypt(payloadByte, encKey)
+	serialize, err := j.encrypt(payloadByte, key.aead)
 	if err != nil {
 		return "", err
 	}
@@ -69,7 +80,7 @@ func (j *JweChaCha20) generate(payloadByte []byte, key []byte) (string, error) {
 	headerB64 := encodeHeaderB64("C20P", serialize.Iv, serialize.Tag)
 
 	// Generate signature
-	signature := HMAC(headerB64, serialize.Cipher, macKey)
+	signature := key.sign(headerB64, serialize.Cipher)
 
 	// Combine header.payload.signature
 	return headerB64 + "." + serialize.Cipher + "." + signature, nil
@@ -89,11 +100,11 @@ func (j *JweChaCha20) Parse(token string, key []byte) (map[string]any, error) {
 	// Parse the decrypted payload
 	claims := map[string]any{}
 	if err = json.Unmarshal(plaintext, &claims); err != nil {
-		return nil, err
+		return nil, payloadError(err)
 	}
 
 	// Validate the registered claims (exp/nbf/iat/iss/aud)
-	if err = validateClaims(claims, j.opts); err != nil {
+	if err = validateClaims(claims, plaintext, token, j.opts); err != nil {
 		return nil, err
 	}
 
@@ -102,50 +113,58 @@ func (j *JweChaCha20) Parse(token string, key []byte) (map[string]any, error) {
 
 // decrypt verifies the signature and returns the raw JSON payload bytes.
 func (j *JweChaCha20) decrypt(token string, key []byte) ([]byte, error) {
-	if err := validateKey(key); err != nil {
+	prepared, err := newPreparedKey(ChaCha20, key)
+	if err != nil {
+		return nil, err
+	}
+	return j.decryptPrepared(token, prepared)
+}
+
+// decryptPrepared verifies the signature with the derived keys held by a
+// PreparedKey and returns the raw JSON payload bytes.
+func (j *JweChaCha20) decryptPrepared(token string, key *PreparedKey) ([]byte, error) {
+	if err := key.forAlg(ChaCha20); err != nil {
 		return nil, err
 	}
 	if len(token) > MaxTokenBytes {
-		return nil, ErrInvalidToken
+		return nil, sizeError(len(token))
 	}
 
-	encKey, macKey := deriveKeys(key)
-
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return nil, ErrInvalidToken
+		return nil, tokenError(StageSize, ErrInvalidToken, segmentsError(len(parts), 3))
 	}
 
 	headerB64, cipherB64, receivedSignature := parts[0], parts[1], parts[2]
 
 	// Decode header
-	headerJSON, err := base64.RawURLEncoding.DecodeString(headerB64)
+	headerJSON, err := j.opts.base64().DecodeString(headerB64)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return nil, tokenError(StageHeader, ErrInvalidToken, err)
 	}
 	var header Header
-	if err := json.Unmarshal(headerJSON, &header); err != nil {
-		return nil, ErrInvalidToken
+	if err := j.opts.decodeHeader(headerJSON, &header); err != nil {
+		return nil, tokenError(StageHeader, ErrInvalidToken, err)
 	}
 
 	// Verify signature using a constant-time comparison to avoid timing attacks
-	expectedSignature := HMAC(headerB64, cipherB64, macKey)
+	expectedSignature := key.sign(headerB64, cipherB64)
 	if !hmac.Equal([]byte(receivedSignature), []byte(expectedSignature)) {
-		return nil, ErrInvalidSignature
+		return nil, tokenError(StageSignature, ErrInvalidSignature, nil)
 	}
 
 	// Decode nonce, ciphertext, and tag
-	nonce, err := base64.RawURLEncoding.DecodeString(header.Iv)
+	nonce, err := j.opts.base64().DecodeString(header.Iv)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return nil, tokenError(StageHeader, ErrInvalidToken, err)
 	}
-	tag, err := base64.RawURLEncoding.DecodeString(header.Tag)
+	tag, err := j.opts.base64().DecodeString(header.Tag)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return nil, tokenError(StageSignature, ErrInvalidToken, err)
 	}
-	ciphertext, err := base64.RawURLEncoding.DecodeString(cipherB64)
+	ciphertext, err := j.opts.base64().DecodeString(cipherB64)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return nil, tokenError(StageDecrypt, ErrInvalidToken, err)
 	}
 
 	// Join ciphertext and tag into a single buffer for decryption
@@ -154,17 +173,46 @@ func (j *JweChaCha20) decrypt(token string, key []byte) ([]byte, error) {
 	fullCiphertext = append(fullCiphertext, tag...)
 
 	// Decrypt payload
-	aead, err := chacha20poly1305.New(encKey)
+	if len(nonce) != key.aead.NonceSize() {
+		return nil, tokenError(StageHeader, ErrInvalidToken, lengthError("nonce", len(nonce), key.aead.NonceSize()))
+	}
+	plaintext, err := key.aead.Open(nil, nonce, fullCiphertext, nil)
 	if err != nil {
+		return nil, tokenError(StageSignature, ErrInvalidSignature, err)
+	}
+
+	if err := j.opts.checkPayload(plaintext); err != nil {
 		return nil, err
 	}
+	return plaintext, nil
+}
 
-	plaintext, err := aead.Open(nil, nonce, fullCiphertext, nil)
+// appendGenerate appends a token for the JSON payload to dst without
+// allocating when dst has enough capacity.
+func (j *JweChaCha20) appendGenerate(dst, payload []byte, key *PreparedKey) ([]byte, error) {
+	if err := key.forAlg(ChaCha20); err != nil {
+		return dst, err
+	}
+	payload, err := j.opts.issue(payload)
 	if err != nil {
-		return nil, ErrInvalidSignature
+		return dst, err
 	}
+	if err := j.opts.recordUsage(key); err != nil {
+		return dst, err
+	}
+	return appendChaChaToken(dst, payload, key, "C20P", j.opts.randReader())
+}
 
-	return plaintext, nil
+// decryptInPlace verifies and decrypts token in place, returning the JSON
+// payload as a sub-slice of token.
+func (j *JweChaCha20) decryptInPlace(token []byte, key *PreparedKey) ([]byte, error) {
+	if err := key.forAlg(ChaCha20); err != nil {
+		return nil, err
+	}
+	if j.opts.strict {
+		return j.decryptPrepared(string(token), key)
+	}
+	return openChaChaTokenInPlace(token, key)
 }
 
 func (j *JweChaCha20) getOptions() options { return j.opts }
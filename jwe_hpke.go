@@ -0,0 +1,184 @@
+package gojwe
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/goccy/go-json"
+	"golang.org/x/crypto/chacha20poly1305"
+	"strings"
+)
+
+// hpkeHeaderAlg is the "alg" header value of HPKE tokens, naming the base-mode
+// suite DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, ChaCha20-Poly1305.
+const hpkeHeaderAlg = "HPKE-Base-X25519-SHA256-ChaCha20Poly1305"
+
+// JweHPKE encrypts tokens to an X25519 recipient public key with HPKE base mode
+// (RFC 9180). Unlike the other algorithms the key is asymmetric: Generate takes
+// the recipient public key and Parse/Verify take the matching private key (see
+// GenerateHPKEKeyPair).
+//
+// Tokens use the compact form header.ciphertext.tag, where the header carries
+// the encapsulated key ("ek") and is bound to the ciphertext as associated data.
+type JweHPKE struct {
+	opts options
+}
+
+func (j *JweHPKE) Generate(payload map[string]any, key []byte) (string, error) {
+	// Convert payload to JSON
+	payloadByte, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return j.generate(payloadByte, key)
+}
+
+// generate encrypts already-marshalled JSON payload bytes to the recipient
+// public key.
+func (j *JweHPKE) generate(payloadByte []byte, key []byte) (string, error) {
+	prepared, err := newPreparedKey(HPKE, key)
+	if err != nil {
+		return "", err
+	}
+	return j.generatePrepared(payloadByte, prepared)
+}
+
+// generatePrepared encrypts already-marshalled JSON payload bytes to the
+// recipient public key held by a PreparedKey.
+func (j *JweHPKE) generatePrepared(payloadByte []byte, prepared *PreparedKey) (string, error) {
+	if err := prepared.forAlg(HPKE); err != nil {
+		return "", err
+	}
+	payloadByte, err := j.opts.issue(payloadByte)
+	if err != nil {
+		return "", err
+	}
+	if err := j.opts.recordUsage(prepared); err != nil {
+		return "", err
+	}
+	key := prepared.key
+
+	// Set up the sender context first: the header carries the encapsulated key
+	// and is authenticated as associated data.
+	enc, aead, nonce, err := hpkeSetupSender(j.opts.randReader(), key, nil)
+	if err != nil {
+		return "", err
+	}
+	headerB64 := encodeHPKEHeaderB64(base64.RawURLEncoding.EncodeToString(enc))
+
+	sealed := aead.Seal(nil, nonce, payloadByte, []byte(headerB64))
+
+	// Split off the authentication tag (last 16 bytes)
+	tagStart := len(sealed) - chacha20poly1305.Overhead
+	cipherB64 := base64.RawURLEncoding.EncodeToString(sealed[:tagStart])
+	tagB64 := base64.RawURLEncoding.EncodeToString(sealed[tagStart:])
+
+	return headerB64 + "." + cipherB64 + "." + tagB64, nil
+}
+
+func (j *JweHPKE) Verify(token string, key []byte) bool {
+	claims, err := j.Parse(token, key)
+	return claims != nil && err == nil
+}
+
+func (j *JweHPKE) Parse(token string, key []byte) (map[string]any, error) {
+	plaintext, err := j.decrypt(token, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the decrypted payload
+	claims := map[string]any{}
+	if err = json.Unmarshal(plaintext, &claims); err != nil {
+		return nil, payloadError(err)
+	}
+
+	// Validate the registered claims (exp/nbf/iat/iss/aud)
+	if err = validateClaims(claims, plaintext, token, j.opts); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// decrypt decapsulates the content key with the recipient private key and
+// returns the raw JSON payload bytes.
+func (j *JweHPKE) decrypt(token string, key []byte) ([]byte, error) {
+	prepared, err := newPreparedKey(HPKE, key)
+	if err != nil {
+		return nil, err
+	}
+	return j.decryptPrepared(token, prepared)
+}
+
+// decryptPrepared decapsulates the content key with the recipient private key
+// held by a PreparedKey and returns the raw JSON payload bytes.
+func (j *JweHPKE) decryptPrepared(token string, prepared *PreparedKey) ([]byte, error) {
+	if err := prepared.forAlg(HPKE); err != nil {
+		return nil, err
+	}
+	key := prepared.key
+	if len(token) > MaxTokenBytes {
+		return nil, sizeError(len(token))
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, tokenError(StageSize, ErrInvalidToken, segmentsError(len(parts), 3))
+	}
+
+	headerB64, cipherB64, tagB64 := parts[0], parts[1], parts[2]
+
+	// Decode header
+	headerJSON, err := j.opts.base64().DecodeString(headerB64)
+	if err != nil {
+		return nil, tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	var header Header
+	if err := j.opts.decodeHeader(headerJSON, &header); err != nil {
+		return nil, tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	if header.Alg != hpkeHeaderAlg {
+		return nil, tokenError(StageHeader, ErrInvalidToken, fmt.Errorf("unexpected alg %q", header.Alg))
+	}
+
+	// Decode encapsulated key, ciphertext, and tag
+	enc, err := j.opts.base64().DecodeString(header.Ek)
+	if err != nil {
+		return nil, tokenError(StageHeader, ErrInvalidToken, err)
+	}
+	if len(enc) != KeySize {
+		return nil, tokenError(StageHeader, ErrInvalidToken, lengthError("encapsulated key", len(enc), KeySize))
+	}
+	ciphertext, err := j.opts.base64().DecodeString(cipherB64)
+	if err != nil {
+		return nil, tokenError(StageDecrypt, ErrInvalidToken, err)
+	}
+	tag, err := j.opts.base64().DecodeString(tagB64)
+	if err != nil {
+		return nil, tokenError(StageSignature, ErrInvalidToken, err)
+	}
+	if len(tag) != chacha20poly1305.Overhead {
+		return nil, tokenError(StageSignature, ErrInvalidToken, lengthError("tag", len(tag), chacha20poly1305.Overhead))
+	}
+
+	// Join ciphertext and tag into a single buffer for decryption
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(sealed, ciphertext...)
+	sealed = append(sealed, tag...)
+
+	aead, nonce, err := hpkeSetupRecipient(enc, key, nil)
+	if err != nil {
+		return nil, tokenError(StageDecrypt, ErrInvalidToken, err)
+	}
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(headerB64))
+	if err != nil {
+		return nil, tokenError(StageSignature, ErrInvalidSignature, err)
+	}
+
+	if err := j.opts.checkPayload(plaintext); err != nil {
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+func (j *JweHPKE) getOptions() options { return j.opts }
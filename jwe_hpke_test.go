@@ -0,0 +1,163 @@
+package gojwe_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/prongbang/gojwe"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	hpkePrivateKey, _ = hex.DecodeString("8057991eef8f1f1af18f4a9491d16a1ce333f695d4db8e38da75975c4478e0fb")
+	hpkePublicKey, _  = hex.DecodeString("4310ee97d88cc1f088a5576c77ab0cf5c3ac797f3d95139c6c84b5429c59662a")
+)
+
+const hpkeToken = "eyJhbGciOiJIUEtFLUJhc2UtWDI1NTE5LVNIQTI1Ni1DaGFDaGEyMFBvbHkxMzA1IiwiZWsiOiJSZGxiZlFKQlo3S1JiV2VMenZHVkNhakwxakx4VnpTRGtIYlFzZzRjMlQ4In0.cMPlN0nJsDRTyTJzMhq9qfI7OA.BmaG-kYwdKqXufMd121b8w"
+
+func TestHPKEGenerate(t *testing.T) {
+	j := gojwe.New(gojwe.HPKE)
+	got, err := j.Generate(map[string]any{"exp": 99999999999}, hpkePublicKey)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !j.Verify(got, hpkePrivateKey) {
+		t.Fatalf("Verify() = false for token %s", got)
+	}
+	// Only the private key can open the token.
+	if j.Verify(got, hpkePublicKey) {
+		t.Fatal("Verify() with the public key = true, want false")
+	}
+}
+
+func TestHPKEParse(t *testing.T) {
+	j := gojwe.New(gojwe.HPKE)
+	got, err := j.Parse(hpkeToken, hpkePrivateKey)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got["exp"] != float64(99999999999) {
+		t.Fatalf("Parse() exp = %v, want 99999999999", got["exp"])
+	}
+}
+
+func TestHPKEKeyPair(t *testing.T) {
+	priv, pub, err := gojwe.GenerateHPKEKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateHPKEKeyPair() error = %v", err)
+	}
+	derived, err := gojwe.HPKEPublicKey(priv)
+	if err != nil || hex.EncodeToString(derived) != hex.EncodeToString(pub) {
+		t.Fatalf("HPKEPublicKey() = %x, %v, want %x", derived, err, pub)
+	}
+
+	j := gojwe.New(gojwe.HPKE)
+	token, _ := gojwe.GenerateClaims(j, gojwe.RegisteredClaims{
+		Subject:   "user-1",
+		ExpiresAt: gojwe.NewNumericDate(time.Now().Add(time.Hour)),
+	}, pub)
+	claims, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, priv)
+	if err != nil || claims.Subject != "user-1" {
+		t.Fatalf("ParseClaims() = %+v, %v", claims, err)
+	}
+}
+
+func TestHPKETamperedHeaderIsRejected(t *testing.T) {
+	j := gojwe.New(gojwe.HPKE)
+	_, otherPub, _ := gojwe.GenerateHPKEKeyPair()
+	other, _ := j.Generate(map[string]any{"sub": "x"}, otherPub)
+
+	// Swap in the header (and thus the encapsulated key) of another token.
+	tampered := other[:strings.IndexByte(other, '.')] + hpkeToken[strings.IndexByte(hpkeToken, '.'):]
+	if _, err := j.Parse(tampered, hpkePrivateKey); !errors.Is(err, gojwe.ErrInvalidSignature) {
+		t.Fatalf("Parse() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+// hpkeVectorA2 is the base-mode test vector of RFC 9180 Appendix A.2.1,
+// DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, ChaCha20Poly1305. Its recipient key
+// pair is hpkePrivateKey / hpkePublicKey.
+var hpkeVectorA2 = struct {
+	info, ikmE, skEm, enc, sharedSecret, key, baseNonce string
+	aad, pt, ct                                         string
+}{
+	info:         "4f6465206f6e2061204772656369616e2055726e",
+	ikmE:         "909a9b35d3dc4713a5e72a4da274b55d3d3821a37e5d099e74a647db583a904b",
+	skEm:         "f4ec9b33b792c372c1d2c2063507b684ef925b8c75a42dbcbf57d63ccd381600",
+	enc:          "1afa08d3dec047a643885163f1180476fa7ddb54c6a8029ea33f95796bf2ac4a",
+	sharedSecret: "0bbe78490412b4bbea4812666f7916932b828bba79942424abb65244930d69a7",
+	key:          "ad2744de8e17f4ebba575b3f5f5a8fa1f69c2a07f6e7500bc60ca6e3e3ec1c91",
+	baseNonce:    "5c4d98150661b848853b547f",
+	aad:          "436f756e742d30", // "Count-0", sequence number 0
+	pt:           "4265617574792069732074727574682c20747275746820626561757479",
+	ct:           "1c5250d8034ec2b784ba2cfd69dbdb8af406cfe3ff938e131f0def8c8b60b4db21993c62ce81883d2dd1b51a28",
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestHPKEVectorKeySchedule(t *testing.T) {
+	v := hpkeVectorA2
+	if skE := gojwe.HPKEDeriveKeyPair(mustHex(t, v.ikmE)); hex.EncodeToString(skE) != v.skEm {
+		t.Fatalf("DeriveKeyPair(ikmE) = %x, want %s", skE, v.skEm)
+	}
+	sharedSecret, err := gojwe.HPKEDecap(mustHex(t, v.enc), hpkePrivateKey)
+	if err != nil || hex.EncodeToString(sharedSecret) != v.sharedSecret {
+		t.Fatalf("Decap() = %x, %v, want %s", sharedSecret, err, v.sharedSecret)
+	}
+	key, baseNonce := gojwe.HPKEKeySchedule(sharedSecret, mustHex(t, v.info))
+	if hex.EncodeToString(key) != v.key || hex.EncodeToString(baseNonce) != v.baseNonce {
+		t.Fatalf("KeySchedule() = %x, %x, want %s, %s", key, baseNonce, v.key, v.baseNonce)
+	}
+}
+
+func TestHPKEVectorSealOpen(t *testing.T) {
+	v := hpkeVectorA2
+	info, aad, pt := mustHex(t, v.info), mustHex(t, v.aad), mustHex(t, v.pt)
+
+	// The sender draws its ephemeral key from the reader.
+	enc, aead, nonce, err := gojwe.HPKESetupSender(bytes.NewReader(mustHex(t, v.skEm)), hpkePublicKey, info)
+	if err != nil {
+		t.Fatalf("SetupSender() error = %v", err)
+	}
+	if hex.EncodeToString(enc) != v.enc {
+		t.Fatalf("SetupSender() enc = %x, want %s", enc, v.enc)
+	}
+	if ct := aead.Seal(nil, nonce, pt, aad); hex.EncodeToString(ct) != v.ct {
+		t.Fatalf("Seal() = %x, want %s", ct, v.ct)
+	}
+
+	aead, nonce, err = gojwe.HPKESetupRecipient(mustHex(t, v.enc), hpkePrivateKey, info)
+	if err != nil {
+		t.Fatalf("SetupRecipient() error = %v", err)
+	}
+	got, err := aead.Open(nil, nonce, mustHex(t, v.ct), aad)
+	if err != nil || !bytes.Equal(got, pt) {
+		t.Fatalf("Open() = %q, %v, want %q", got, err, pt)
+	}
+}
+
+func BenchmarkHPKEGenerate(b *testing.B) {
+	j := gojwe.New(gojwe.HPKE)
+	payload := map[string]any{
+		"exp": 999999999,
+	}
+	for i := 0; i < b.N; i++ {
+		_, _ = j.Generate(payload, hpkePublicKey)
+	}
+}
+
+func BenchmarkHPKEParse(b *testing.B) {
+	j := gojwe.New(gojwe.HPKE)
+	for i := 0; i < b.N; i++ {
+		_, _ = j.Parse(hpkeToken, hpkePrivateKey)
+	}
+}
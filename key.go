@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"io"
 )
 
 // KeySize is the required key length in bytes for every supported algorithm.
@@ -47,8 +48,15 @@ func deriveKeys(master []byte) (encKey, macKey []byte) {
 // suitable for any algorithm supported by this package. It replaces the
 // need to run "openssl rand -hex 32" manually.
 func GenerateKey() ([]byte, error) {
+	return GenerateKeyFromReader(rand.Reader)
+}
+
+// GenerateKeyFromReader is like GenerateKey but reads the key from r. It is
+// meant for reproducible test vectors together with WithRand; production code
+// should use GenerateKey.
+func GenerateKeyFromReader(r io.Reader) ([]byte, error) {
 	key := make([]byte, KeySize)
-	if _, err := rand.Read(key); err != nil {
+	if _, err := io.ReadFull(r, key); err != nil {
 		return nil, err
 	}
 	return key, nil
@@ -0,0 +1,169 @@
+package gojwe
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// MapClaims is a decoded claims set with typed accessors. Unlike the map
+// returned by Parse, it decodes JSON numbers as json.Number, so 64-bit
+// integers such as database IDs keep their exact value:
+//
+//	claims, err := gojwe.ParseMapClaims(j, token, key)
+//	userID, err := claims.GetInt64("uid")
+//
+// The accessors return a *ClaimError wrapping ErrMissingClaim when the claim is
+// absent (or null) and ErrInvalidClaimType when it has another type.
+type MapClaims map[string]any
+
+// ParseMapClaims is like Parse but returns MapClaims, keeping numbers exact.
+func ParseMapClaims(j JWE, token string, key []byte) (MapClaims, error) {
+	return ParseClaims[MapClaims](j, token, key)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, decoding numbers
+// as json.Number.
+func (m *MapClaims) UnmarshalJSON(b []byte) error {
+	var claims map[string]any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return err
+	}
+	*m = claims
+	return nil
+}
+
+// lookup returns the non-null value of the claim name.
+func (m MapClaims) lookup(name string) (any, error) {
+	v, ok := m[name]
+	if !ok || v == nil {
+		return nil, &ClaimError{Claim: name, Err: ErrMissingClaim}
+	}
+	return v, nil
+}
+
+// typeError reports a claim that does not have the wanted type.
+func typeError(name, want string, v any) error {
+	return &ClaimError{Claim: name, Expected: want, Actual: v, Err: ErrInvalidClaimType}
+}
+
+// GetString returns the string claim name.
+func (m MapClaims) GetString(name string) (string, error) {
+	v, err := m.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", typeError(name, "string", v)
+	}
+	return s, nil
+}
+
+// GetInt64 returns the integer claim name. Numbers with a fraction or outside
+// the int64 range are rejected rather than rounded.
+func (m MapClaims) GetInt64(name string) (int64, error) {
+	v, err := m.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := toInt64(v)
+	if !ok {
+		return 0, typeError(name, "int64", v)
+	}
+	return n, nil
+}
+
+// GetTime returns the NumericDate claim name, such as "exp".
+func (m MapClaims) GetTime(name string) (time.Time, error) {
+	v, err := m.lookup(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, ok := toUnixTime(v, 0)
+	if !ok {
+		return time.Time{}, typeError(name, "NumericDate", v)
+	}
+	return t, nil
+}
+
+// GetStrings returns the claim name holding a string array or a single
+// string, such as "aud".
+func (m MapClaims) GetStrings(name string) ([]string, error) {
+	v, err := m.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := claimValues(v, false)
+	if !ok {
+		return nil, typeError(name, "[]string", v)
+	}
+	return s, nil
+}
+
+// GetBool returns the boolean claim name.
+func (m MapClaims) GetBool(name string) (bool, error) {
+	v, err := m.lookup(name)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, typeError(name, "bool", v)
+	}
+	return b, nil
+}
+
+// toInt64 converts an integral claim value to int64.
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		// Parsed exactly: an integer, optionally with an all-zero fraction.
+		// Exponents and anything a float64 would round are rejected.
+		whole, frac, _ := strings.Cut(string(n), ".")
+		if strings.Trim(frac, "0") != "" {
+			return 0, false
+		}
+		i, err := strconv.ParseInt(whole, 10, 64)
+		return i, err == nil
+	case float64:
+		return floatToInt64(n, true)
+	case float32:
+		return floatToInt64(float64(n), true)
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case uint:
+		return int64(n), uint64(n) <= math.MaxInt64
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case uint32:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// floatToInt64 converts f when it is integral and within the int64 range.
+func floatToInt64(f float64, ok bool) (int64, bool) {
+	if !ok || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
@@ -0,0 +1,124 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/prongbang/gojwe"
+)
+
+func TestParseMapClaims(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg)
+		token, err := j.Generate(map[string]any{
+			"uid":   int64(9007199254740993),
+			"sub":   "user-1",
+			"aud":   []string{"api", "web"},
+			"admin": true,
+			"exp":   exp.Unix(),
+		}, genKey)
+		if err != nil {
+			t.Fatalf("[%s] Generate() error = %v", alg, err)
+		}
+
+		claims, err := gojwe.ParseMapClaims(j, token, parseKey)
+		if err != nil {
+			t.Fatalf("[%s] ParseMapClaims() error = %v", alg, err)
+		}
+		if uid, err := claims.GetInt64("uid"); err != nil || uid != 9007199254740993 {
+			t.Fatalf("[%s] GetInt64() = %d, %v", alg, uid, err)
+		}
+		if sub, err := claims.GetString("sub"); err != nil || sub != "user-1" {
+			t.Fatalf("[%s] GetString() = %q, %v", alg, sub, err)
+		}
+		if aud, err := claims.GetStrings("aud"); err != nil || len(aud) != 2 || aud[1] != "web" {
+			t.Fatalf("[%s] GetStrings() = %v, %v", alg, aud, err)
+		}
+		if admin, err := claims.GetBool("admin"); err != nil || !admin {
+			t.Fatalf("[%s] GetBool() = %v, %v", alg, admin, err)
+		}
+		if got, err := claims.GetTime("exp"); err != nil || !got.Equal(exp) {
+			t.Fatalf("[%s] GetTime() = %v, %v", alg, got, err)
+		}
+	}
+}
+
+func TestMapClaimsErrors(t *testing.T) {
+	var claims gojwe.MapClaims
+	if err := json.Unmarshal([]byte(`{"sub":42,"n":1.5,"big":1e30,"exp1":1e3,"none":null,"aud":[1]}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := claims.GetString("missing")
+	var ce *gojwe.ClaimError
+	if !errors.Is(err, gojwe.ErrMissingClaim) || !errors.As(err, &ce) || ce.Claim != "missing" {
+		t.Fatalf("GetString(missing) error = %v, want ErrMissingClaim", err)
+	}
+	if _, err := claims.GetBool("none"); !errors.Is(err, gojwe.ErrMissingClaim) {
+		t.Fatalf("GetBool(null) error = %v, want ErrMissingClaim", err)
+	}
+	for name, get := range map[string]func() error{
+		"GetString(sub)":  func() error { _, err := claims.GetString("sub"); return err },
+		"GetInt64(n)":     func() error { _, err := claims.GetInt64("n"); return err },
+		"GetInt64(big)":   func() error { _, err := claims.GetInt64("big"); return err },
+		"GetInt64(exp1)":  func() error { _, err := claims.GetInt64("exp1"); return err },
+		"GetStrings(aud)": func() error { _, err := claims.GetStrings("aud"); return err },
+		"GetTime(aud)":    func() error { _, err := claims.GetTime("aud"); return err },
+	} {
+		if err := get(); !errors.Is(err, gojwe.ErrInvalidClaimType) {
+			t.Errorf("%s error = %v, want ErrInvalidClaimType", name, err)
+		}
+	}
+}
+
+func TestMapClaimsInt64ZeroFraction(t *testing.T) {
+	var claims gojwe.MapClaims
+	// Read exactly, where a float64 would give 12345678901234568.
+	if err := json.Unmarshal([]byte(`{"uid":12345678901234567.0,"half":12345678901234567.5}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := claims.GetInt64("uid"); err != nil || got != 12345678901234567 {
+		t.Fatalf("GetInt64(uid) = %d, %v, want 12345678901234567", got, err)
+	}
+	if _, err := claims.GetInt64("half"); !errors.Is(err, gojwe.ErrInvalidClaimType) {
+		t.Fatalf("GetInt64(half) error = %v, want ErrInvalidClaimType", err)
+	}
+}
+
+func TestMapClaimsTimeTypes(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	claims := gojwe.MapClaims{
+		"u64":  uint64(1700000000),
+		"time": at,
+		"nd":   gojwe.NewNumericDate(at),
+		"num":  json.Number("1700000000"),
+	}
+	for name := range claims {
+		if got, err := claims.GetTime(name); err != nil || !got.Equal(at) {
+			t.Errorf("GetTime(%s) = %v, %v, want %v", name, got, err, at)
+		}
+	}
+}
+
+func TestValidateTimeClaimTypes(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	past := time.Now().Add(-time.Hour)
+	for name, exp := range map[string]any{
+		"uint64":       uint64(past.Unix()),
+		"*NumericDate": gojwe.NewNumericDate(past),
+	} {
+		token, err := j.Generate(map[string]any{"exp": exp}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Errorf("[%s] Parse() error = %v, want ErrTokenExpired", name, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package gojwe
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// MatchMode selects how accepted issuers and audiences are compared with the
+// token's "iss" and "aud" claims.
+type MatchMode int
+
+const (
+	// MatchExact requires the claim to equal an accepted value. It is the
+	// default.
+	MatchExact MatchMode = iota
+
+	// MatchGlob treats accepted values as host name patterns, either bare
+	// ("*.example.com") or inside a URL ("https://*.example.com"). Patterns
+	// apply per DNS label with path.Match syntax, so '*' matches within one
+	// label: "https://*.example.com" accepts "https://api.example.com" but not
+	// "https://a.b.example.com" or "https://evil.com#.example.com". The rest of
+	// a URL (scheme, port, path, query and fragment) must match exactly, and
+	// claims with user info are rejected. Malformed patterns match nothing.
+	MatchGlob
+
+	// MatchPrefix accepts claims that start with an accepted value at a path
+	// boundary: the claim equals the value, the value ends in '/', or the
+	// claim continues with '/', '?' or '#'. So "https://auth.example.com"
+	// accepts "https://auth.example.com/tenant-1" but not
+	// "https://auth.example.com.evil.io".
+	MatchPrefix
+)
+
+// match reports whether the claim value v satisfies the accepted value want.
+func (m MatchMode) match(want, v string) bool {
+	switch m {
+	case MatchGlob:
+		return globMatch(want, v)
+	case MatchPrefix:
+		return prefixMatch(want, v)
+	default:
+		return v == want
+	}
+}
+
+// prefixMatch reports whether v starts with want at a path boundary.
+func prefixMatch(want, v string) bool {
+	if !strings.HasPrefix(v, want) {
+		return false
+	}
+	if len(v) == len(want) || strings.HasSuffix(want, "/") {
+		return true
+	}
+	return strings.IndexByte("/?#", v[len(want)]) >= 0
+}
+
+// globMatch reports whether v satisfies the MatchGlob pattern want.
+func globMatch(want, v string) bool {
+	if !strings.Contains(want, "://") {
+		return globHost(want, v)
+	}
+	p, err := url.Parse(want)
+	if err != nil {
+		return false
+	}
+	u, err := url.Parse(v)
+	if err != nil || u.User != nil || u.Opaque != "" {
+		return false
+	}
+	return u.Scheme == p.Scheme && u.Port() == p.Port() &&
+		u.EscapedPath() == p.EscapedPath() && u.RawQuery == p.RawQuery &&
+		u.ForceQuery == p.ForceQuery && u.Fragment == p.Fragment &&
+		globHost(p.Hostname(), u.Hostname())
+}
+
+// globHost matches the host name host label by label against pattern. Every
+// label of host must be a non-empty DNS label.
+func globHost(pattern, host string) bool {
+	want, got := strings.Split(pattern, "."), strings.Split(host, ".")
+	if len(want) != len(got) {
+		return false
+	}
+	for i, label := range got {
+		if !isDNSLabel(label) {
+			return false
+		}
+		ok, err := path.Match(strings.ToLower(want[i]), strings.ToLower(label))
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// isDNSLabel reports whether s is a non-empty label of letters, digits, '-'
+// and '_'.
+func isDNSLabel(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny reports whether v satisfies at least one of the accepted values.
+func (m MatchMode) matchAny(accepted []string, v string) bool {
+	for _, want := range accepted {
+		if m.match(want, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// issuerAccepted reports whether iss satisfies one of the issuers configured
+// with WithIssuer/WithIssuers. A missing issuer is never accepted.
+func issuerAccepted(iss string, opts options) bool {
+	return iss != "" && opts.matchMode.matchAny(opts.issuers, iss)
+}
+
+// audienceAccepted reports whether the raw "aud" claim satisfies the audiences
+// configured with WithAudience, WithAnyAudience or WithAllAudiences.
+func audienceAccepted(aud any, opts options) bool {
+	if opts.allAudiences {
+		for _, want := range opts.audiences {
+			if !audienceContains(aud, want, opts.matchMode) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, want := range opts.audiences {
+		if audienceContains(aud, want, opts.matchMode) {
+			return true
+		}
+	}
+	return false
+}
+
+// audienceContains reports whether the raw "aud" claim (a string or an array of
+// strings, as produced by JSON decoding) contains a value matching want.
+func audienceContains(v any, want string, mode MatchMode) bool {
+	switch a := v.(type) {
+	case string:
+		return mode.match(want, a)
+	case []any:
+		for _, item := range a {
+			if s, ok := item.(string); ok && mode.match(want, s) {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range a {
+			if mode.match(want, s) {
+				return true
+			}
+		}
+	case ClaimStrings:
+		for _, s := range a {
+			if mode.match(want, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestWithIssuers(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithIssuers("https://a.example.com", "https://b.example.com", "https://c.example.com"))
+		for iss, want := range map[string]error{
+			"https://b.example.com":    nil,
+			"https://evil.example.com": gojwe.ErrInvalidIssuer,
+			"":                         gojwe.ErrInvalidIssuer,
+		} {
+			token, _ := j.Generate(map[string]any{"iss": iss}, genKey)
+			if _, err := j.Parse(token, parseKey); !errors.Is(err, want) {
+				t.Fatalf("[%s] Parse() with iss %q error = %v, want %v", alg, iss, err, want)
+			}
+			if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey); !errors.Is(err, want) {
+				t.Fatalf("[%s] ParseClaims() with iss %q error = %v, want %v", alg, iss, err, want)
+			}
+		}
+	}
+}
+
+func TestAudienceModes(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	tests := []struct {
+		name string
+		opt  gojwe.Option
+		aud  any
+		want error
+	}{
+		{"any matches one", gojwe.WithAnyAudience("web", "mobile"), []string{"api", "mobile"}, nil},
+		{"any matches string", gojwe.WithAnyAudience("web", "mobile"), "web", nil},
+		{"any matches none", gojwe.WithAnyAudience("web", "mobile"), []string{"api"}, gojwe.ErrInvalidAudience},
+		{"all present", gojwe.WithAllAudiences("web", "api"), []string{"api", "web", "cli"}, nil},
+		{"all missing one", gojwe.WithAllAudiences("web", "api"), []string{"web"}, gojwe.ErrInvalidAudience},
+		{"absent", gojwe.WithAnyAudience("web"), nil, gojwe.ErrInvalidAudience},
+	}
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		for _, tt := range tests {
+			j := gojwe.New(alg, tt.opt)
+			claims := map[string]any{}
+			if tt.aud != nil {
+				claims["aud"] = tt.aud
+			}
+			token, _ := j.Generate(claims, genKey)
+			if _, err := j.Parse(token, parseKey); !errors.Is(err, tt.want) {
+				t.Fatalf("[%s] %s: Parse() error = %v, want %v", alg, tt.name, err, tt.want)
+			}
+			if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey); !errors.Is(err, tt.want) {
+				t.Fatalf("[%s] %s: ParseClaims() error = %v, want %v", alg, tt.name, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestWithMatchMode(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	tests := []struct {
+		name string
+		opts []gojwe.Option
+		iss  string
+		want error
+	}{
+		{"exact ignores patterns", []gojwe.Option{gojwe.WithIssuer("https://*.example.com")}, "https://api.example.com", gojwe.ErrInvalidIssuer},
+		{"glob", []gojwe.Option{gojwe.WithIssuer("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://api.example.com", nil},
+		{"glob does not cross slash", []gojwe.Option{gojwe.WithIssuer("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://evil.com/.example.com", gojwe.ErrInvalidIssuer},
+		{"glob does not cross fragment", []gojwe.Option{gojwe.WithIssuer("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://evil.com#.example.com", gojwe.ErrInvalidIssuer},
+		{"glob does not cross query", []gojwe.Option{gojwe.WithIssuer("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://evil.com?.example.com", gojwe.ErrInvalidIssuer},
+		{"glob rejects user info", []gojwe.Option{gojwe.WithIssuer("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://x.example.com@evil.com", gojwe.ErrInvalidIssuer},
+		{"glob does not cross port", []gojwe.Option{gojwe.WithIssuer("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://evil.com:.example.com", gojwe.ErrInvalidIssuer},
+		{"glob matches one label", []gojwe.Option{gojwe.WithIssuer("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://a.b.example.com", gojwe.ErrInvalidIssuer},
+		{"glob host only", []gojwe.Option{gojwe.WithIssuer("*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "API.example.com", nil},
+		{"glob host only mismatch", []gojwe.Option{gojwe.WithIssuer("*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "evil.com#.example.com", gojwe.ErrInvalidIssuer},
+		{"bad pattern", []gojwe.Option{gojwe.WithIssuer("https://[.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob)}, "https://[.example.com", gojwe.ErrInvalidIssuer},
+		{"prefix", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com/"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.com/tenant-1", nil},
+		{"prefix mismatch", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com/"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.org/", gojwe.ErrInvalidIssuer},
+		{"prefix equal", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.com", nil},
+		{"prefix path boundary", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.com/tenant-1", nil},
+		{"prefix query boundary", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.com?tenant=1", nil},
+		{"prefix does not extend host", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.com.evil.io", gojwe.ErrInvalidIssuer},
+		{"prefix does not extend port", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.com:8443", gojwe.ErrInvalidIssuer},
+		{"prefix does not extend segment", []gojwe.Option{gojwe.WithIssuer("https://auth.example.com/t"), gojwe.WithMatchMode(gojwe.MatchPrefix)}, "https://auth.example.com/tenant-1", gojwe.ErrInvalidIssuer},
+	}
+	for _, tt := range tests {
+		j := gojwe.New(gojwe.ChaCha20, tt.opts...)
+		token, _ := j.Generate(map[string]any{"iss": tt.iss, "aud": []string{"https://api.example.com"}}, key)
+		if _, err := j.Parse(token, key); !errors.Is(err, tt.want) {
+			t.Fatalf("%s: Parse() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// The mode applies to audiences too.
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithAnyAudience("https://*.example.com"), gojwe.WithMatchMode(gojwe.MatchGlob))
+	token, _ := j.Generate(map[string]any{"aud": []string{"https://api.example.com"}}, key)
+	if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, key); err != nil {
+		t.Fatalf("ParseClaims() error = %v", err)
+	}
+}
+
+func TestWithIssuersCopiesArguments(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	issuers := []string{"https://a.example.com"}
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithIssuers(issuers...))
+	issuers[0] = "https://evil.example.com"
+
+	token, _ := j.Generate(map[string]any{"iss": "https://evil.example.com"}, key)
+	if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrInvalidIssuer) {
+		t.Fatalf("Parse() error = %v, want ErrInvalidIssuer", err)
+	}
+}
@@ -1,6 +1,10 @@
 package gojwe
 
-import "time"
+import (
+	"crypto/rand"
+	"io"
+	"time"
+)
 
 // DefaultLeeway is the clock-skew tolerance applied when validating the
 // time-based claims ("exp" and "nbf"). It absorbs small clock differences
@@ -12,8 +16,40 @@ type options struct {
 	leeway       time.Duration
 	validateTime bool
 	validateIat  bool
-	expectedIss  string
-	expectedAud  string
+	issuers      []string
+	audiences    []string
+	allAudiences bool
+	matchMode    MatchMode
+	constraints  []claimConstraint
+	rand         io.Reader
+	clock        Clock
+	precision    time.Duration
+
+	allValidationErrors bool
+
+	strict   bool
+	maxDepth int
+
+	issuance issuance
+
+	requiredScopes []string
+	requiredRoles  []string
+
+	usageStore       UsageStore
+	usageSoftLimit   uint64
+	usageHardLimit   uint64
+	onUsageSoftLimit func(keyID string, count uint64)
+
+	replayStore   ReplayStore
+	revoker       Revoker
+	sessionEpochs SessionEpochStore
+
+	validators     []func(claims map[string]any) error
+	requiredClaims []string
+	maxAge         time.Duration
+
+	// expiryGrace extends "exp" for Refresh's WithRefreshGrace.
+	expiryGrace time.Duration
 }
 
 func defaultOptions() options {
@@ -25,7 +61,43 @@ func defaultOptions() options {
 
 // needsValidation reports whether any claim validation is configured.
 func (o options) needsValidation() bool {
-	return o.validateTime || o.expectedIss != "" || o.expectedAud != ""
+	return o.validateTime || len(o.issuers) > 0 || len(o.audiences) > 0 ||
+		len(o.constraints) > 0 || len(o.requiredClaims) > 0 || o.maxAge > 0 ||
+		len(o.requiredScopes) > 0 || len(o.requiredRoles) > 0 ||
+		len(o.validators) > 0 ||
+		o.replayStore != nil || o.revoker != nil || o.sessionEpochs != nil
+}
+
+// now returns the current time of the configured Clock.
+func (o options) now() time.Time {
+	if o.clock != nil {
+		return o.clock.Now()
+	}
+	return systemClock{}.Now()
+}
+
+// numericDate returns the NumericDate value of t for a generated token: whole
+// seconds, or truncated to the precision set with WithTimePrecision.
+func (o options) numericDate(t time.Time) *NumericDate {
+	return NewNumericDateWithPrecision(t, o.precision)
+}
+
+// truncate truncates t to the precision of the NumericDate values this
+// instance writes.
+func (o options) truncate(t time.Time) time.Time {
+	if o.precision <= 0 {
+		return t.Truncate(time.Second)
+	}
+	return t.Truncate(o.precision)
+}
+
+// randReader returns the source used for nonces, content keys and ephemeral
+// keys: the reader set with WithRand, or crypto/rand.
+func (o options) randReader() io.Reader {
+	if o.rand != nil {
+		return o.rand
+	}
+	return rand.Reader
 }
 
 // Option configures a JWE instance created by New / NewWithError.
@@ -54,13 +126,260 @@ func WithIssuedAtValidation() Option {
 // WithIssuer requires the token's "iss" claim to equal the given issuer.
 // Tokens with a different or missing issuer are rejected with ErrInvalidIssuer.
 func WithIssuer(iss string) Option {
-	return func(o *options) { o.expectedIss = iss }
+	if iss == "" {
+		return WithIssuers()
+	}
+	return WithIssuers(iss)
+}
+
+// WithIssuers requires the token's "iss" claim to match one of the given
+// issuers, e.g. when a gateway accepts tokens from several identity providers.
+// Tokens with a different or missing issuer are rejected with ErrInvalidIssuer.
+// It replaces any issuer set earlier.
+func WithIssuers(issuers ...string) Option {
+	issuers = append([]string(nil), issuers...)
+	return func(o *options) { o.issuers = issuers }
 }
 
 // WithAudience requires the token's "aud" claim to contain the given audience.
 // Tokens without it are rejected with ErrInvalidAudience.
 func WithAudience(aud string) Option {
-	return func(o *options) { o.expectedAud = aud }
+	if aud == "" {
+		return WithAnyAudience()
+	}
+	return WithAnyAudience(aud)
+}
+
+// WithAnyAudience requires the token's "aud" claim to contain at least one of
+// the given audiences. Tokens without any are rejected with ErrInvalidAudience.
+// It replaces any audience set earlier.
+func WithAnyAudience(audiences ...string) Option {
+	audiences = append([]string(nil), audiences...)
+	return func(o *options) { o.audiences, o.allAudiences = audiences, false }
+}
+
+// WithAllAudiences requires the token's "aud" claim to contain every one of the
+// given audiences. Tokens missing one are rejected with ErrInvalidAudience. It
+// replaces any audience set earlier.
+func WithAllAudiences(audiences ...string) Option {
+	audiences = append([]string(nil), audiences...)
+	return func(o *options) { o.audiences, o.allAudiences = audiences, true }
+}
+
+// WithSubject requires the token's "sub" claim to equal sub, e.g. to accept
+// only a given service account. Tokens with another or no subject are rejected
+// with a *ClaimMismatchError matching ErrInvalidSubject and ErrClaimMismatch.
+func WithSubject(sub string) Option {
+	return func(o *options) {
+		o.constraints = append(o.constraints, claimConstraint{name: "sub", accepted: []any{sub}, err: ErrInvalidSubject})
+	}
+}
+
+// WithClaimEquals requires the claim name to equal value, e.g.
+// WithClaimEquals("token_use", "access"). Strings, booleans and numbers are
+// compared by value; a []string value requires an array claim with the same
+// strings in any order, and a scalar value also accepts an array claim that
+// contains it. Tokens that do not match are rejected with a *ClaimMismatchError
+// matching ErrClaimMismatch. Constraints accumulate.
+func WithClaimEquals(name string, value any) Option {
+	return WithClaimIn(name, value)
+}
+
+// WithClaimIn requires the claim name to match one of values, compared as with
+// WithClaimEquals, e.g. WithClaimIn("tid", "tenant-a", "tenant-b").
+func WithClaimIn(name string, values ...any) Option {
+	return func(o *options) {
+		o.constraints = append(o.constraints, claimConstraint{name: name, accepted: values})
+	}
+}
+
+// WithRequiredScopes requires the token's space-delimited "scope" claim to
+// contain every one of scopes. Tokens lacking one are rejected with
+// ErrInsufficientScope. Calls accumulate.
+func WithRequiredScopes(scopes ...string) Option {
+	return func(o *options) { o.requiredScopes = append(o.requiredScopes, scopes...) }
+}
+
+// WithRequiredRoles requires the token's "roles" claim to contain every one of
+// roles. Tokens lacking one are rejected with ErrInsufficientScope. To accept
+// any of several roles, use WithValidator with HasAnyRole. Calls accumulate.
+func WithRequiredRoles(roles ...string) Option {
+	return func(o *options) { o.requiredRoles = append(o.requiredRoles, roles...) }
+}
+
+// WithMatchMode selects how the values given to WithIssuer(s) and the audience
+// options are compared with the token's claims: exactly (the default), as
+// path.Match glob patterns such as "https://*.example.com", or as prefixes.
+func WithMatchMode(m MatchMode) Option {
+	return func(o *options) { o.matchMode = m }
+}
+
+// WithRequiredClaims rejects tokens that lack any of the named claims with
+// ErrMissingClaim, e.g. WithRequiredClaims("exp", "sub", "jti"). Without it a
+// missing claim means "no constraint"; in particular a token without "exp"
+// never expires. Calls accumulate.
+func WithRequiredClaims(names ...string) Option {
+	return func(o *options) { o.requiredClaims = append(o.requiredClaims, names...) }
+}
+
+// WithMaxAge bounds how long any token is accepted, whatever its "exp" says.
+// Tokens whose "iat" is more than d (plus leeway) in the past, or that were
+// issued with a lifetime (exp - iat) longer than d, are rejected with
+// ErrTokenTooOld. Tokens without "iat" are rejected with ErrMissingClaim.
+func WithMaxAge(d time.Duration) Option {
+	return func(o *options) { o.maxAge = d }
+}
+
+// WithTTL makes Generate and GenerateClaims set "exp" to the current time plus
+// d when the payload has none.
+func WithTTL(d time.Duration) Option {
+	return func(o *options) { o.issuance.ttl = d }
+}
+
+// WithIssuedAtNow makes Generate and GenerateClaims set "iat" to the current
+// time when the payload has none.
+func WithIssuedAtNow() Option {
+	return func(o *options) { o.issuance.issuedAt = true }
+}
+
+// WithNotBeforeNow makes Generate and GenerateClaims set "nbf" to the current
+// time when the payload has none.
+func WithNotBeforeNow() Option {
+	return func(o *options) { o.issuance.notBefore = true }
+}
+
+// WithJTI makes Generate and GenerateClaims set "jti" to a fresh identifier
+// from gen when the payload has none, e.g. WithJTI(gojwe.UUIDv7). gen receives
+// the issuance time (see WithClock) and the random source (see WithRand).
+func WithJTI(gen func(now time.Time, r io.Reader) (string, error)) Option {
+	return func(o *options) { o.issuance.jti = gen }
+}
+
+// WithDefaultIssuer makes Generate and GenerateClaims set "iss" when the
+// payload has none.
+func WithDefaultIssuer(iss string) Option {
+	return func(o *options) { o.issuance.issuer = iss }
+}
+
+// WithDefaultAudience makes Generate and GenerateClaims set "aud" when the
+// payload has none: a single audience as a string, several as an array.
+func WithDefaultAudience(aud ...string) Option {
+	aud = append([]string(nil), aud...)
+	return func(o *options) { o.issuance.audience = aud }
+}
+
+// WithAllValidationErrors makes validation run every check instead of stopping
+// at the first failure, so the returned *ValidationError lists everything that
+// is wrong with a token, e.g. both an expired "exp" and a wrong "aud". Replay
+// protection is never applied to a token that failed another check.
+func WithAllValidationErrors() Option {
+	return func(o *options) { o.allValidationErrors = true }
+}
+
+// WithStrictDecoding rejects tokens that different JSON parsers could read
+// differently: headers and payloads with duplicate member names, payloads that
+// are not a single JSON object, header members that are neither understood nor
+// listed in "crit", base64url segments with non-zero trailing bits, and JSON
+// nested deeper than WithMaxJSONDepth. Strict decoding copies the token, so
+// ParseBytes no longer decrypts in place.
+func WithStrictDecoding() Option {
+	return func(o *options) { o.strict = true }
+}
+
+// WithMaxJSONDepth sets the nesting limit of WithStrictDecoding (default
+// DefaultMaxJSONDepth).
+func WithMaxJSONDepth(n int) Option {
+	return func(o *options) { o.maxDepth = n }
+}
+
+// WithClock makes the instance read the current time from c instead of the
+// system clock when validating exp, nbf, iat and the maximum age. Use a
+// FakeClock to test expiry without sleeping or touching package state.
+func WithClock(c Clock) Option {
+	return func(o *options) { o.clock = c }
+}
+
+// WithTimePrecision writes and compares the time claims ("exp", "nbf", "iat"
+// and "auth_time") at precision d instead of whole seconds, for tokens that
+// live only a few seconds. With time.Millisecond, WithTTL, WithIssuedAtNow,
+// WithNotBeforeNow, Refresh and TokenIssuer write 1700000000.123, as does
+// GenerateClaims for the "exp", "nbf" and "iat" of typed claims, and
+// validation truncates the claims of parsed tokens to milliseconds. Any d is
+// accepted: times are truncated to a multiple of d and written with the digits
+// it needs, e.g. 1700000000.75 for 250ms. Zero or less means whole seconds.
+func WithTimePrecision(d time.Duration) Option {
+	return func(o *options) { o.precision = d }
+}
+
+// WithRand routes every random draw made while generating tokens (nonces, the
+// AES content-encryption key and key-wrap IV, HPKE ephemeral keys) through r
+// instead of crypto/rand, so that tokens become reproducible.
+//
+// It exists for known-answer tests and test vectors only. Never use it in
+// production: a predictable or repeating reader reuses nonces and destroys the
+// confidentiality and integrity of every token. With AESGCM256 the token is
+// built by this package instead of lestrrat-go/jwx, producing the same RFC 7516
+// compact serialization.
+func WithRand(r io.Reader) Option {
+	return func(o *options) { o.rand = r }
+}
+
+// WithReplayProtection makes every token single-use: Parse/Verify (and
+// ParseClaims) require a "jti" claim, record it in store on the first
+// successful parse until the token's "exp" plus leeway, and reject later uses
+// with ErrTokenReplayed. Tokens without "jti" fail with ErrMissingClaim. The
+// jti of a token without "exp" is remembered forever.
+//
+// Use NewMemoryReplayStore for a single instance, or a shared ReplayStore when
+// several instances verify the same tokens.
+func WithReplayProtection(store ReplayStore) Option {
+	return func(o *options) { o.replayStore = store }
+}
+
+// WithRevoker consults r after decryption and rejects tokens it reports as
+// revoked, by "jti" or by Fingerprint, with ErrTokenRevoked.
+func WithRevoker(r Revoker) Option {
+	return func(o *options) { o.revoker = r }
+}
+
+// WithSessionEpochs consults store after decryption and rejects tokens whose
+// "iat" is older than the epoch of their "sub" with ErrSessionInvalidated, so
+// that all earlier tokens of a subject can be invalidated at once.
+func WithSessionEpochs(store SessionEpochStore) Option {
+	return func(o *options) { o.sessionEpochs = store }
+}
+
+// WithValidator adds an application-specific check that runs on the decoded
+// claims after the registered claims have been validated, e.g. "tenant must
+// match the subdomain". A non-nil error is reported by Parse, ParseClaims and
+// friends inside a *ValidationError, so errors.Is and errors.As still find it,
+// and makes Verify report false. Validators run in the order they were added.
+func WithValidator(fn func(claims map[string]any) error) Option {
+	return func(o *options) { o.validators = append(o.validators, fn) }
+}
+
+// WithKeyUsage counts every token encrypted by Generate (and GenerateClaims)
+// per key in store, keyed by KeyID. Combine it with WithKeyUsageSoftLimit and
+// WithKeyUsageHardLimit to know when a key must be rotated: ChaCha20 with
+// random 96-bit nonces is only safe for roughly 2^32 messages per key.
+func WithKeyUsage(store UsageStore) Option {
+	return func(o *options) { o.usageStore = store }
+}
+
+// WithKeyUsageSoftLimit calls fn once, synchronously, when a key reaches n
+// encrypted tokens. Generation keeps working; use it to schedule rotation.
+// Without WithKeyUsage the counters are kept in memory.
+func WithKeyUsageSoftLimit(n uint64, fn func(keyID string, count uint64)) Option {
+	return func(o *options) {
+		o.usageSoftLimit = n
+		o.onUsageSoftLimit = fn
+	}
+}
+
+// WithKeyUsageHardLimit makes Generate fail with ErrKeyUsageExceeded once a key
+// has encrypted n tokens. Without WithKeyUsage the counters are kept in memory.
+func WithKeyUsageHardLimit(n uint64) Option {
+	return func(o *options) { o.usageHardLimit = n }
 }
 
 func applyOptions(opts []Option) options {
@@ -68,5 +387,8 @@ func applyOptions(opts []Option) options {
 	for _, opt := range opts {
 		opt(&o)
 	}
+	if o.usageStore == nil && (o.usageSoftLimit > 0 || o.usageHardLimit > 0) {
+		o.usageStore = NewMemoryUsageStore()
+	}
 	return o
 }
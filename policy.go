@@ -0,0 +1,82 @@
+package gojwe
+
+// checkRequired rejects tokens lacking any of the claims configured with
+// WithRequiredClaims. Registered claims are looked up in c; other names in the
+// full payload.
+func checkRequired(c *claimSet, opts options, v *validation) error {
+	for _, name := range opts.requiredClaims {
+		present, err := c.has(name)
+		if err != nil {
+			return err
+		}
+		if !present && v.fail(&ClaimError{Claim: name, Err: ErrMissingClaim}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// checkMaxAge enforces WithMaxAge: the token must carry "iat", must not be
+// older than the maximum age and must not have been issued with a lifetime
+// (exp - iat) longer than it. The leeway applies to the age only, since the
+// lifetime is computed from the issuer's clock alone.
+func checkMaxAge(c *claimSet, opts options, v *validation) error {
+	if opts.maxAge <= 0 {
+		return nil
+	}
+	if c.iat.IsZero() {
+		v.fail(&ClaimError{Claim: "iat", Err: ErrMissingClaim})
+		return nil
+	}
+	if excess := v.now.Sub(c.iat) - (opts.maxAge + opts.leeway); excess > 0 &&
+		v.fail(&ClaimError{Claim: "iat", Expected: v.now, Actual: c.iat, Delta: excess, Err: ErrTokenTooOld}) {
+		return nil
+	}
+	if !c.exp.IsZero() {
+		if excess := c.exp.Sub(c.iat) - opts.maxAge; excess > 0 {
+			v.fail(&ClaimError{Claim: "exp", Expected: c.iat.Add(opts.maxAge), Actual: c.exp, Delta: excess, Err: ErrTokenTooOld})
+		}
+	}
+	return nil
+}
+
+// has reports whether the claim name is present in the token.
+func (c *claimSet) has(name string) (bool, error) {
+	switch name {
+	case "exp":
+		return !c.exp.IsZero(), nil
+	case "nbf":
+		return !c.nbf.IsZero(), nil
+	case "iat":
+		return !c.iat.IsZero(), nil
+	case "iss":
+		return c.iss != "", nil
+	case "sub":
+		return c.sub != "", nil
+	case "jti":
+		return c.jti != "", nil
+	case "aud":
+		return audiencePresent(c.aud), nil
+	}
+	claims, err := c.payload()
+	if err != nil {
+		return false, err
+	}
+	_, ok := claims[name]
+	return ok, nil
+}
+
+// audiencePresent reports whether the raw "aud" claim holds at least one value.
+func audiencePresent(v any) bool {
+	switch a := v.(type) {
+	case string:
+		return a != ""
+	case []any:
+		return len(a) > 0
+	case []string:
+		return len(a) > 0
+	case ClaimStrings:
+		return len(a) > 0
+	}
+	return false
+}
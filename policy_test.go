@@ -0,0 +1,71 @@
+package gojwe_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestWithRequiredClaims(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	exp := time.Now().Add(time.Hour).Unix()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithRequiredClaims("exp", "sub", "jti"), gojwe.WithRequiredClaims("tenant"))
+
+		good, _ := j.Generate(map[string]any{"exp": exp, "sub": "user-1", "jti": "1", "tenant": "acme"}, genKey)
+		if _, err := j.Parse(good, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() error = %v", alg, err)
+		}
+		if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, good, parseKey); err != nil {
+			t.Fatalf("[%s] ParseClaims() error = %v", alg, err)
+		}
+
+		for _, missing := range []string{"exp", "sub", "jti", "tenant"} {
+			claims := map[string]any{"exp": exp, "sub": "user-1", "jti": "1", "tenant": "acme"}
+			delete(claims, missing)
+			token, _ := j.Generate(claims, genKey)
+
+			_, err := j.Parse(token, parseKey)
+			if !errors.Is(err, gojwe.ErrMissingClaim) || !strings.Contains(err.Error(), missing) {
+				t.Fatalf("[%s] Parse() without %s error = %v, want ErrMissingClaim", alg, missing, err)
+			}
+			_, err = gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey)
+			if !errors.Is(err, gojwe.ErrMissingClaim) {
+				t.Fatalf("[%s] ParseClaims() without %s error = %v, want ErrMissingClaim", alg, missing, err)
+			}
+		}
+	}
+}
+
+func TestWithMaxAge(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	now := time.Now()
+	tests := []struct {
+		name   string
+		claims map[string]any
+		want   error
+	}{
+		{"fresh", map[string]any{"iat": now.Unix(), "exp": now.Add(time.Hour).Unix()}, nil},
+		{"fresh without exp", map[string]any{"iat": now.Add(-time.Hour).Unix()}, nil},
+		{"too old", map[string]any{"iat": now.Add(-3 * time.Hour).Unix()}, gojwe.ErrTokenTooOld},
+		{"lifetime too long", map[string]any{"iat": now.Unix(), "exp": now.Add(24 * time.Hour).Unix()}, gojwe.ErrTokenTooOld},
+		{"missing iat", map[string]any{"exp": now.Add(time.Hour).Unix()}, gojwe.ErrMissingClaim},
+	}
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithMaxAge(2*time.Hour))
+		for _, tt := range tests {
+			token, _ := j.Generate(tt.claims, genKey)
+			if _, err := j.Parse(token, parseKey); !errors.Is(err, tt.want) {
+				t.Fatalf("[%s] %s: Parse() error = %v, want %v", alg, tt.name, err, tt.want)
+			}
+			if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey); !errors.Is(err, tt.want) {
+				t.Fatalf("[%s] %s: ParseClaims() error = %v, want %v", alg, tt.name, err, tt.want)
+			}
+		}
+	}
+}
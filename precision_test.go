@@ -0,0 +1,142 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/prongbang/gojwe"
+)
+
+func TestTimePrecisionEncoding(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, tc := range []struct {
+		at        time.Time
+		precision time.Duration
+		want      string
+	}{
+		{time.Unix(1700000000, 123456789), 0, "1700000000"},
+		{time.Unix(1700000000, 123456789), time.Second, "1700000000"},
+		{time.Unix(1700000000, 123456789), time.Millisecond, "1700000000.123"},
+		{time.Unix(1700000000, 123456789), time.Microsecond, "1700000000.123456"},
+		{time.Unix(1700000000, 123456789), time.Nanosecond, "1700000000.123456789"},
+		{time.Unix(-1, 750*int64(time.Millisecond)), time.Millisecond, "-0.250"},
+		{time.Unix(1700000000, 800*int64(time.Millisecond)), 250 * time.Millisecond, "1700000000.75"},
+		{time.Unix(1700000000, 800*int64(time.Millisecond)), 1500 * time.Millisecond, "1699999999.5"},
+	} {
+		j := gojwe.New(gojwe.ChaCha20, gojwe.WithoutTimeValidation(), gojwe.WithIssuedAtNow(),
+			gojwe.WithClock(gojwe.NewFakeClock(tc.at)), gojwe.WithTimePrecision(tc.precision))
+		token, err := j.Generate(map[string]any{"sub": "worker"}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := gojwe.ParseMapClaims(j, token, key)
+		if err != nil || claims["iat"] != json.Number(tc.want) {
+			t.Fatalf("[%v] iat = %v, %v, want %s", tc.precision, claims["iat"], err, tc.want)
+		}
+	}
+}
+
+func TestNumericDateWithPrecision(t *testing.T) {
+	at := time.Unix(1700000000, 123456789)
+	b, err := json.Marshal(gojwe.NewNumericDateWithPrecision(at, time.Millisecond))
+	if err != nil || string(b) != "1700000000.123" {
+		t.Fatalf("Marshal() = %s, %v, want 1700000000.123", b, err)
+	}
+	if d := gojwe.NewNumericDateWithPrecision(at, 0); !d.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("NewNumericDateWithPrecision(0) = %v, want whole seconds", d.Time)
+	}
+}
+
+func TestGenerateClaimsTimePrecision(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	exp := time.Unix(1700000001, 500*int64(time.Millisecond))
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithClock(clock), gojwe.WithLeeway(0), gojwe.WithTimePrecision(time.Millisecond))
+		claims := gojwe.RegisteredClaims{Subject: "worker", ExpiresAt: &gojwe.NumericDate{Time: exp}}
+
+		token, err := gojwe.GenerateClaims(j, claims, genKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prepared, _ := gojwe.PrepareKey(alg, genKey)
+		preparedToken, err := gojwe.GenerateClaimsWithKey(j, &claims, prepared)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, token := range []string{token, preparedToken} {
+			raw, err := gojwe.ParseMapClaims(j, token, parseKey)
+			if err != nil || raw["exp"] != json.Number("1700000001.500") || raw["sub"] != "worker" {
+				t.Fatalf("[%s] claims = %v, %v, want exp 1700000001.500", alg, raw, err)
+			}
+			got, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey)
+			if err != nil || !got.ExpiresAt.Equal(exp) {
+				t.Fatalf("[%s] ParseClaims() = %+v, %v, want exp %v", alg, got, err, exp)
+			}
+		}
+	}
+}
+
+func TestNumericDateDefaults(t *testing.T) {
+	// Without WithTimePrecision, NumericDate writes whole seconds and keeps the
+	// fraction it reads.
+	b, err := json.Marshal(gojwe.NumericDate{Time: time.Unix(1700000000, 900*int64(time.Millisecond))})
+	if err != nil || string(b) != "1700000000" {
+		t.Fatalf("Marshal() = %s, %v, want 1700000000", b, err)
+	}
+	var got gojwe.NumericDate
+	if err := json.Unmarshal([]byte("1700000000.123456"), &got); err != nil || got.Nanosecond() != 123456000 {
+		t.Fatalf("Unmarshal(1700000000.123456) = %v, %v", got.Time, err)
+	}
+}
+
+func TestSubSecondExpiry(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		clock.Set(time.Unix(1700000000, 0))
+		j := gojwe.New(alg, gojwe.WithClock(clock), gojwe.WithLeeway(0), gojwe.WithTTL(1500*time.Millisecond), gojwe.WithIssuedAtNow(), gojwe.WithTimePrecision(time.Millisecond))
+
+		token, err := j.Generate(map[string]any{"sub": "worker"}, genKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mapToken, err := gojwe.GenerateClaims(j, gojwe.RegisteredClaims{Subject: "worker"}, genKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		clock.Advance(1400 * time.Millisecond)
+		if _, err := j.Parse(token, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() at 1.4s error = %v", alg, err)
+		}
+		claims, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, mapToken, parseKey)
+		if err != nil {
+			t.Fatalf("[%s] ParseClaims() at 1.4s error = %v", alg, err)
+		}
+		if want := time.Unix(1700000001, 500*int64(time.Millisecond)); !claims.ExpiresAt.Equal(want) {
+			t.Fatalf("[%s] exp = %v, want %v", alg, claims.ExpiresAt.Time, want)
+		}
+		if _, err := gojwe.ParseClaims[struct{ Sub string }](j, mapToken, parseKey); err != nil {
+			t.Fatalf("[%s] ParseClaims(untyped) at 1.4s error = %v", alg, err)
+		}
+
+		clock.Advance(200 * time.Millisecond)
+		if _, err := j.Parse(token, parseKey); !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Fatalf("[%s] Parse() at 1.6s error = %v, want ErrTokenExpired", alg, err)
+		}
+		if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, mapToken, parseKey); !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Fatalf("[%s] ParseClaims() at 1.6s error = %v, want ErrTokenExpired", alg, err)
+		}
+		if _, err := gojwe.ParseClaims[struct{ Sub string }](j, mapToken, parseKey); !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Fatalf("[%s] ParseClaims(untyped) at 1.6s error = %v, want ErrTokenExpired", alg, err)
+		}
+		if _, err := gojwe.ParseMapClaims(j, token, parseKey); !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Fatalf("[%s] ParseMapClaims() at 1.6s error = %v, want ErrTokenExpired", alg, err)
+		}
+	}
+}
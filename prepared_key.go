@@ -0,0 +1,190 @@
+package gojwe
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// PreparedKey is a key bound to one algorithm with all per-key work done up
+// front: for ChaCha20 and XChaCha20 the encryption and MAC keys are derived
+// once, the AEAD is constructed once and HMAC instances are pooled. Reuse it
+// across requests with GenerateWithKey, ParseWithKey and the other *WithKey
+// helpers to save that work on every call.
+//
+// A PreparedKey is immutable and safe for concurrent use.
+type PreparedKey struct {
+	alg string
+	key []byte
+
+	// ChaCha20 / XChaCha20 only.
+	aead   cipher.AEAD
+	macKey []byte
+	macs   sync.Pool // *macState
+
+	idOnce sync.Once
+	id     string
+}
+
+// macState is a pooled HMAC-SHA256 instance keyed with the MAC key, plus
+// scratch space for the signature so signing does not allocate.
+type macState struct {
+	h   hash.Hash
+	sum [sha256.Size]byte
+	sig [signatureB64Len]byte
+}
+
+// PrepareKey validates key for alg and precomputes its derived material. The
+// key bytes are copied, so the caller may reuse the slice. For HPKE the key is
+// the recipient public key when generating and the private key when parsing.
+func PrepareKey(alg string, key []byte) (*PreparedKey, error) {
+	switch alg {
+	case AESGCM256, ChaCha20, XChaCha20, HPKE:
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return newPreparedKey(alg, append([]byte(nil), key...))
+}
+
+// newPreparedKey is PrepareKey without the defensive copy, used by the
+// per-call Generate/Parse paths that own the key for the duration of the call.
+func newPreparedKey(alg string, key []byte) (*PreparedKey, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	k := &PreparedKey{alg: alg, key: key}
+
+	var err error
+	switch alg {
+	case ChaCha20, XChaCha20:
+		// Derive independent encryption and MAC keys (key separation)
+		encKey, macKey := deriveKeys(key)
+		if alg == ChaCha20 {
+			k.aead, err = chacha20poly1305.New(encKey)
+		} else {
+			k.aead, err = chacha20poly1305.NewX(encKey)
+		}
+		k.macKey = macKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Algorithm returns the algorithm the key was prepared for.
+func (k *PreparedKey) Algorithm() string { return k.alg }
+
+// keyID returns KeyID of the raw key, computed once on first use.
+func (k *PreparedKey) keyID() string {
+	k.idOnce.Do(func() { k.id = KeyID(k.key) })
+	return k.id
+}
+
+// forAlg returns ErrKeyAlgorithmMismatch unless the key was prepared for alg.
+func (k *PreparedKey) forAlg(alg string) error {
+	if k.alg != alg {
+		return ErrKeyAlgorithmMismatch
+	}
+	return nil
+}
+
+// getMAC returns a reset HMAC-SHA256 keyed with the derived MAC key.
+func (k *PreparedKey) getMAC() *macState {
+	if m, ok := k.macs.Get().(*macState); ok {
+		m.h.Reset()
+		return m
+	}
+	return &macState{h: hmac.New(sha256.New, k.macKey)}
+}
+
+func (k *PreparedKey) putMAC(m *macState) { k.macs.Put(m) }
+
+// sign is HMAC(header, payload, macKey) using a pooled HMAC instance.
+func (k *PreparedKey) sign(header, payload string) string {
+	m := k.getMAC()
+	defer k.putMAC(m)
+	m.h.Write([]byte(header))
+	m.h.Write([]byte{'.'})
+	m.h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(m.h.Sum(m.sum[:0]))
+}
+
+// GenerateWithKey is like j.Generate but uses a PreparedKey, skipping the
+// per-call key derivation for the built-in algorithms.
+func GenerateWithKey(j JWE, payload map[string]any, key *PreparedKey) (string, error) {
+	pc, ok := j.(preparedCodec)
+	if !ok {
+		// Fallback for custom JWE implementations.
+		return j.Generate(payload, key.key)
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return pc.generatePrepared(b, key)
+}
+
+// ParseWithKey is like j.Parse but uses a PreparedKey, skipping the per-call
+// key derivation for the built-in algorithms.
+func ParseWithKey(j JWE, token string, key *PreparedKey) (map[string]any, error) {
+	pc, ok := j.(preparedCodec)
+	if !ok {
+		// Fallback for custom JWE implementations.
+		return j.Parse(token, key.key)
+	}
+	b, err := pc.decryptPrepared(token, key)
+	if err != nil {
+		return nil, err
+	}
+	claims := map[string]any{}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, payloadError(err)
+	}
+	if err := validateClaims(claims, b, token, pc.getOptions()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// VerifyWithKey is like j.Verify but uses a PreparedKey.
+func VerifyWithKey(j JWE, token string, key *PreparedKey) bool {
+	claims, err := ParseWithKey(j, token, key)
+	return claims != nil && err == nil
+}
+
+// GenerateClaimsWithKey is like GenerateClaims but uses a PreparedKey.
+func GenerateClaimsWithKey(j JWE, claims any, key *PreparedKey) (string, error) {
+	pc, ok := j.(preparedCodec)
+	if !ok {
+		return GenerateClaims(j, claims, key.key)
+	}
+	b, err := marshalClaims(claims, pc.getOptions())
+	if err != nil {
+		return "", err
+	}
+	if err := checkGeneratedTags(claims, b); err != nil {
+		return "", err
+	}
+	return pc.generatePrepared(b, key)
+}
+
+// ParseClaimsWithKey is like ParseClaims but uses a PreparedKey.
+func ParseClaimsWithKey[T any](j JWE, token string, key *PreparedKey) (T, error) {
+	pc, ok := j.(preparedCodec)
+	if !ok {
+		return ParseClaims[T](j, token, key.key)
+	}
+	b, err := pc.decryptPrepared(token, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return decodeClaims[T](b, token, pc.getOptions())
+}
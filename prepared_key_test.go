@@ -0,0 +1,138 @@
+package gojwe_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestPreparedKeyRoundTrip(t *testing.T) {
+	raw := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		j := gojwe.New(alg)
+		genRaw, parseRaw := keysFor(alg, raw)
+		genKey, err := gojwe.PrepareKey(alg, genRaw)
+		if err != nil {
+			t.Fatalf("[%s] PrepareKey() error = %v", alg, err)
+		}
+		parseKey, _ := gojwe.PrepareKey(alg, parseRaw)
+
+		token, err := gojwe.GenerateWithKey(j, map[string]any{"sub": "user-1"}, genKey)
+		if err != nil {
+			t.Fatalf("[%s] GenerateWithKey() error = %v", alg, err)
+		}
+		// Tokens are interchangeable with the raw-key API.
+		claims, err := j.Parse(token, parseRaw)
+		if err != nil || claims["sub"] != "user-1" {
+			t.Fatalf("[%s] Parse() = %v, %v", alg, claims, err)
+		}
+
+		token, _ = j.Generate(map[string]any{"sub": "user-2"}, genRaw)
+		claims, err = gojwe.ParseWithKey(j, token, parseKey)
+		if err != nil || claims["sub"] != "user-2" {
+			t.Fatalf("[%s] ParseWithKey() = %v, %v", alg, claims, err)
+		}
+		if !gojwe.VerifyWithKey(j, token, parseKey) {
+			t.Fatalf("[%s] VerifyWithKey() = false, want true", alg)
+		}
+	}
+}
+
+func TestPreparedKeyClaims(t *testing.T) {
+	j := gojwe.New(gojwe.XChaCha20)
+	key, _ := gojwe.PrepareKey(gojwe.XChaCha20, gojwe.MustGenerateKey())
+
+	token, err := gojwe.GenerateClaimsWithKey(j, gojwe.RegisteredClaims{
+		Subject:   "user-1",
+		ExpiresAt: gojwe.NewNumericDate(time.Now().Add(-time.Hour)),
+	}, key)
+	if err != nil {
+		t.Fatalf("GenerateClaimsWithKey() error = %v", err)
+	}
+	if _, err := gojwe.ParseClaimsWithKey[gojwe.RegisteredClaims](j, token, key); !errors.Is(err, gojwe.ErrTokenExpired) {
+		t.Fatalf("ParseClaimsWithKey() error = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestPreparedKeyErrors(t *testing.T) {
+	if _, err := gojwe.PrepareKey("nope", gojwe.MustGenerateKey()); !errors.Is(err, gojwe.ErrUnsupportedAlgorithm) {
+		t.Fatalf("PrepareKey() error = %v, want ErrUnsupportedAlgorithm", err)
+	}
+	if _, err := gojwe.PrepareKey(gojwe.ChaCha20, []byte("short")); !errors.Is(err, gojwe.ErrInvalidKeySize) {
+		t.Fatalf("PrepareKey() error = %v, want ErrInvalidKeySize", err)
+	}
+
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, gojwe.MustGenerateKey())
+	for _, alg := range []string{gojwe.AESGCM256, gojwe.XChaCha20, gojwe.HPKE} {
+		if _, err := gojwe.GenerateWithKey(gojwe.New(alg), map[string]any{}, key); !errors.Is(err, gojwe.ErrKeyAlgorithmMismatch) {
+			t.Fatalf("[%s] GenerateWithKey() error = %v, want ErrKeyAlgorithmMismatch", alg, err)
+		}
+	}
+}
+
+func TestPreparedKeyCopiesKey(t *testing.T) {
+	raw := gojwe.MustGenerateKey()
+	original := append([]byte(nil), raw...)
+	key, _ := gojwe.PrepareKey(gojwe.AESGCM256, raw)
+	raw[0] ^= 0xff
+
+	j := gojwe.New(gojwe.AESGCM256)
+	token, _ := gojwe.GenerateWithKey(j, map[string]any{"sub": "x"}, key)
+	if _, err := j.Parse(token, original); err != nil {
+		t.Fatalf("Parse() with the original key error = %v", err)
+	}
+}
+
+func TestPreparedKeyConcurrentUse(t *testing.T) {
+	j := gojwe.New(gojwe.ChaCha20)
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, gojwe.MustGenerateKey())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				token, err := gojwe.GenerateWithKey(j, map[string]any{"n": i}, key)
+				if err == nil {
+					_, err = gojwe.ParseWithKey(j, token, key)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent use error = %v", err)
+	}
+}
+
+func BenchmarkChaCha20GenerateWithKey(b *testing.B) {
+	j := gojwe.New(gojwe.ChaCha20)
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, chaCha20Key)
+	payload := map[string]any{
+		"exp": 999999999,
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = gojwe.GenerateWithKey(j, payload, key)
+	}
+}
+
+func BenchmarkChaCha20ParseWithKey(b *testing.B) {
+	j := gojwe.New(gojwe.ChaCha20)
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, chaCha20Key)
+	jwe := "eyJhbGciOiJkaXIiLCJlbmMiOiJDMjBQIiwiaXYiOiIyU0M3aFhtQTVScGxIZllwIiwidGFnIjoiWjV6dVB1REZLVDU4LTZqajFiVENIUSJ9.8N8nO9Oh0jpWv2aXQRz9qrUKIw.q7tqbWfPsWTI97wP6xPSeoJTcPiBlVDNzOFmp4qXNlo"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = gojwe.ParseWithKey(j, jwe, key)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package gojwe_test
+
+// The race detector makes sync.Pool drop items at random, so allocation
+// counts are meaningless under -race.
+func init() { raceEnabled = true }
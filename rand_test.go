@@ -0,0 +1,65 @@
+package gojwe_test
+
+import (
+	"testing"
+
+	"github.com/prongbang/gojwe"
+)
+
+// countingReader is a deterministic, insecure byte source for known-answer
+// tests: it yields 0x00, 0x01, 0x02, ... wrapping at 0xff.
+type countingReader struct{ next byte }
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.next
+		r.next++
+	}
+	return len(p), nil
+}
+
+func TestWithRandIsReproducible(t *testing.T) {
+	key, _ := gojwe.GenerateKeyFromReader(&countingReader{})
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		a, err := gojwe.New(alg, gojwe.WithRand(&countingReader{})).Generate(map[string]any{"sub": "kat"}, genKey)
+		if err != nil {
+			t.Fatalf("[%s] Generate() error = %v", alg, err)
+		}
+		b, _ := gojwe.New(alg, gojwe.WithRand(&countingReader{})).Generate(map[string]any{"sub": "kat"}, genKey)
+		if a != b {
+			t.Fatalf("[%s] tokens differ with the same reader:\n%s\n%s", alg, a, b)
+		}
+
+		// The token must still be readable by a regular instance.
+		claims, err := gojwe.New(alg).Parse(a, parseKey)
+		if err != nil || claims["sub"] != "kat" {
+			t.Fatalf("[%s] Parse() = %v, %v", alg, claims, err)
+		}
+	}
+}
+
+func TestWithRandHPKE(t *testing.T) {
+	priv, pub, _ := gojwe.GenerateHPKEKeyPairFromReader(&countingReader{next: 0x80})
+	a, _ := gojwe.New(gojwe.HPKE, gojwe.WithRand(&countingReader{})).Generate(map[string]any{"sub": "kat"}, pub)
+	b, _ := gojwe.New(gojwe.HPKE, gojwe.WithRand(&countingReader{})).Generate(map[string]any{"sub": "kat"}, pub)
+	if a != b {
+		t.Fatalf("tokens differ with the same reader:\n%s\n%s", a, b)
+	}
+	if _, err := gojwe.New(gojwe.HPKE).Parse(a, priv); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+}
+
+func TestWithRandKnownAnswer(t *testing.T) {
+	const want = "eyJhbGciOiJkaXIiLCJlbmMiOiJDMjBQIiwiaXYiOiJBQUVDQXdRRkJnY0lDUW9MIiwidGFnIjoiNlZDMVNDUjRBOGs0VmFFV29yNmI5ZyJ9.z6fPYd0hwcVFseSOg7YheQvX-w.hKk2kpWhLPsXYYyF36woqMuZVKul7I6rCPSoEBabyXY"
+
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithRand(&countingReader{}))
+	got, err := j.Generate(map[string]any{"exp": 99999999999}, chaCha20Key)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got != want {
+		t.Fatalf("Generate() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,220 @@
+package gojwe
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// RefreshOption configures Refresh.
+type RefreshOption func(*refreshOptions)
+
+type refreshOptions struct {
+	window     time.Duration
+	grace      time.Duration
+	ttl        time.Duration
+	maxSession time.Duration
+}
+
+// WithRefreshWindow only reissues tokens that expire within d; Refresh returns
+// tokens with more life left unchanged. By default every token is reissued.
+func WithRefreshWindow(d time.Duration) RefreshOption {
+	return func(o *refreshOptions) { o.window = d }
+}
+
+// WithRefreshGrace accepts tokens that expired at most d ago (on top of the
+// instance leeway), so a client returning after a short pause can still slide
+// its session forward.
+func WithRefreshGrace(d time.Duration) RefreshOption {
+	return func(o *refreshOptions) { o.grace = d }
+}
+
+// WithRefreshTTL sets the lifetime of reissued tokens. It defaults to the
+// instance's WithTTL and otherwise to the lifetime (exp - iat) of the token
+// being refreshed.
+func WithRefreshTTL(d time.Duration) RefreshOption {
+	return func(o *refreshOptions) { o.ttl = d }
+}
+
+// WithMaxSession caps the whole session at d from its "auth_time": tokens are
+// never reissued to expire later than auth_time + d, and refreshing after that
+// point fails with ErrSessionExpired.
+func WithMaxSession(d time.Duration) RefreshOption {
+	return func(o *refreshOptions) { o.maxSession = d }
+}
+
+// Refresh parses token with j and key and reissues it with a new "iat", "exp"
+// and, when the token carries one or WithJTI is set, "jti", keeping every
+// other claim as it was. The new token is encrypted with the same algorithm.
+// For HPKE, key is the recipient private key; the public key to encrypt to is
+// derived from it.
+//
+// The first refresh records the token's "iat" (or the current time) as
+// "auth_time", the start of the session that WithMaxSession limits. The token
+// is validated with the instance options like Parse, except that
+// WithRefreshGrace extends its expiry. Under WithReplayProtection a reissued
+// token consumes its "jti", while a token returned unchanged (see
+// WithRefreshWindow) or whose reissue fails stays usable. Tokens without "exp" cannot be refreshed.
+func Refresh(j JWE, token string, key []byte, opts ...RefreshOption) (string, error) {
+	var ro refreshOptions
+	for _, opt := range opts {
+		opt(&ro)
+	}
+
+	claims, o, consume, err := parseForReissue(j, token, key, func(o *options) { o.expiryGrace = ro.grace })
+	if err != nil {
+		return "", err
+	}
+	refreshed, err := refreshClaims(claims, o, ro)
+	if err != nil || refreshed == nil {
+		return token, err
+	}
+	// Consume the jti only once the replacement exists, so that a failed
+	// reissue leaves the caller's token usable.
+	next, err := reissue(j, refreshed, key)
+	if err != nil {
+		return "", err
+	}
+	if err := consume(); err != nil {
+		return "", err
+	}
+	return next, nil
+}
+
+// parseForReissue decrypts and validates token like Parse, keeping numbers as
+// json.Number so claims copied into a new token are reproduced exactly, and
+// returns the instance options. adjust, if not nil, changes the options the
+// token is validated with, e.g. to extend its expiry. Custom JWE
+// implementations fall back to Parse, without adjust.
+//
+// The replay check is left to consume, which records the token's "jti" under
+// WithReplayProtection; call it once the token is actually replaced.
+func parseForReissue(j JWE, token string, key []byte, adjust func(*options)) (claims map[string]any, o options, consume func() error, err error) {
+	consume = func() error { return nil }
+	rc, ok := j.(rawCodec)
+	if !ok {
+		claims, err := j.Parse(token, key)
+		return claims, defaultOptions(), consume, err
+	}
+	o = rc.getOptions()
+	if adjust != nil {
+		adjust(&o)
+	}
+	b, err := rc.decrypt(token, key)
+	if err != nil {
+		return nil, o, consume, err
+	}
+	claims = map[string]any{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, o, consume, payloadError(err)
+	}
+
+	store := o.replayStore
+	o.replayStore = nil
+	if err := validateClaims(claims, b, token, o); err != nil {
+		return nil, o, consume, err
+	}
+	if store != nil {
+		// Taken before the claims are updated for reissuing.
+		c := mapClaimSet(claims, b, token, o.precision)
+		consume = func() error {
+			ro := o
+			ro.replayStore = store
+			v := &validation{now: ro.now()}
+			if err := checkReplay(c, ro, v); err != nil {
+				return err
+			}
+			return claimsError(v.err())
+		}
+		// A token that Parse rejects for lacking a "jti" is rejected here too.
+		if c.jti == "" {
+			return nil, o, consume, consume()
+		}
+	}
+	return claims, o, consume, nil
+}
+
+// reissue encrypts claims with j. key is the key tokens are parsed with; for
+// HPKE that is the private key, and the public key is derived from it.
+func reissue(j JWE, claims map[string]any, key []byte) (string, error) {
+	if algorithmOf(j) == HPKE {
+		pub, err := HPKEPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		key = pub
+	}
+	return j.Generate(claims, key)
+}
+
+// refreshClaims updates the time and identity claims of a validated token for
+// reissuing. It returns nil claims when the token is outside the refresh
+// window and should be kept.
+func refreshClaims(claims map[string]any, o options, ro refreshOptions) (map[string]any, error) {
+	now := o.now()
+	exp, ok := toUnixTime(claims["exp"], o.precision)
+	if !ok {
+		return nil, claimFailure(&ClaimError{Claim: "exp", Err: ErrMissingClaim})
+	}
+	if ro.window > 0 && exp.Sub(now) > ro.window {
+		return nil, nil
+	}
+
+	iat, hasIat := toUnixTime(claims["iat"], o.precision)
+	ttl := ro.ttl
+	if ttl <= 0 {
+		ttl = o.issuance.ttl
+	}
+	if ttl <= 0 {
+		if !hasIat {
+			return nil, claimFailure(&ClaimError{Claim: "iat", Err: ErrMissingClaim})
+		}
+		ttl = exp.Sub(iat)
+	}
+
+	authTime, ok := toUnixTime(claims["auth_time"], o.precision)
+	if !ok {
+		authTime = now
+		if hasIat {
+			authTime = iat
+		}
+		claims["auth_time"] = o.numericDate(authTime)
+	}
+	newExp := now.Add(ttl)
+	if ro.maxSession > 0 {
+		deadline := authTime.Add(ro.maxSession)
+		if !now.Before(deadline) {
+			return nil, claimFailure(&ClaimError{
+				Claim: "auth_time", Expected: now, Actual: authTime,
+				Delta: now.Sub(deadline), Err: ErrSessionExpired,
+			})
+		}
+		if newExp.After(deadline) {
+			newExp = deadline
+		}
+	}
+	claims["iat"] = o.numericDate(now)
+	claims["exp"] = o.numericDate(newExp)
+
+	if _, hasJti := claims["jti"]; hasJti || o.issuance.jti != nil {
+		gen := o.issuance.jti
+		if gen == nil {
+			gen = UUIDv4
+		}
+		id, err := gen(now, o.randReader())
+		if err != nil {
+			return nil, err
+		}
+		claims["jti"] = id
+	}
+	return claims, nil
+}
+
+// claimFailure reports a token rejected outside validate like a failed claim
+// check.
+func claimFailure(e *ClaimError) error {
+	return claimsError(&ValidationError{Failures: []*ClaimError{e}})
+}
@@ -0,0 +1,193 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestRefresh(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithClock(clock))
+		start := clock.Now()
+		token, err := j.Generate(map[string]any{
+			"sub": "user-1", "jti": "first", "role": "admin", "n": 9007199254740993,
+			"iat": start.Unix(), "exp": start.Add(time.Hour).Unix(),
+		}, genKey)
+		if err != nil {
+			t.Fatalf("[%s] Generate() error = %v", alg, err)
+		}
+
+		clock.Advance(30 * time.Minute)
+		refreshed, err := gojwe.Refresh(j, token, parseKey)
+		if err != nil {
+			t.Fatalf("[%s] Refresh() error = %v", alg, err)
+		}
+		claims, err := j.Parse(refreshed, parseKey)
+		if err != nil {
+			t.Fatalf("[%s] Parse() error = %v", alg, err)
+		}
+		now := clock.Now()
+		if claims["iat"] != float64(now.Unix()) || claims["exp"] != float64(now.Add(time.Hour).Unix()) {
+			t.Fatalf("[%s] iat = %v, exp = %v", alg, claims["iat"], claims["exp"])
+		}
+		if claims["auth_time"] != float64(start.Unix()) {
+			t.Fatalf("[%s] auth_time = %v, want %d", alg, claims["auth_time"], start.Unix())
+		}
+		if claims["jti"] == "first" || claims["jti"] == "" {
+			t.Fatalf("[%s] jti = %v, want a new one", alg, claims["jti"])
+		}
+		if claims["sub"] != "user-1" || claims["role"] != "admin" {
+			t.Fatalf("[%s] custom claims lost: %v", alg, claims)
+		}
+
+		typed, err := gojwe.ParseClaims[struct{ N int64 }](j, refreshed, parseKey)
+		if err != nil || typed.N != 9007199254740993 {
+			t.Fatalf("[%s] n = %d, %v, want exact", alg, typed.N, err)
+		}
+	}
+}
+
+func TestRefreshWindowAndGrace(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(clock), gojwe.WithLeeway(0), gojwe.WithTTL(time.Hour))
+	token, err := j.Generate(map[string]any{"sub": "user-1"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := gojwe.Refresh(j, token, key, gojwe.WithRefreshWindow(10*time.Minute))
+	if err != nil || got != token {
+		t.Fatalf("Refresh() early = changed %v, %v, want the same token", got != token, err)
+	}
+
+	clock.Advance(time.Hour + time.Minute)
+	if _, err := gojwe.Refresh(j, token, key); !errors.Is(err, gojwe.ErrTokenExpired) {
+		t.Fatalf("Refresh() expired error = %v, want ErrTokenExpired", err)
+	}
+	got, err = gojwe.Refresh(j, token, key, gojwe.WithRefreshGrace(5*time.Minute))
+	if err != nil {
+		t.Fatalf("Refresh() within grace error = %v", err)
+	}
+	if _, err := j.Parse(got, key); err != nil {
+		t.Fatalf("Parse() refreshed error = %v", err)
+	}
+}
+
+func TestRefreshWindowWithReplayProtection(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(clock), gojwe.WithTTL(time.Hour), gojwe.WithIssuedAtNow(),
+		gojwe.WithJTI(gojwe.UUIDv4), gojwe.WithReplayProtection(gojwe.NewMemoryReplayStore()))
+	token, err := j.Generate(map[string]any{"sub": "user-1"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token returned unchanged keeps its jti unused.
+	got, err := gojwe.Refresh(j, token, key, gojwe.WithRefreshWindow(10*time.Minute))
+	if err != nil || got != token {
+		t.Fatalf("Refresh() early = changed %v, %v, want the same token", got != token, err)
+	}
+	if _, err := j.Parse(got, key); err != nil {
+		t.Fatalf("Parse() of the unchanged token error = %v", err)
+	}
+
+	// A reissued token consumes it.
+	token, _ = j.Generate(map[string]any{"sub": "user-1"}, key)
+	clock.Advance(55 * time.Minute)
+	got, err = gojwe.Refresh(j, token, key, gojwe.WithRefreshWindow(10*time.Minute))
+	if err != nil || got == token {
+		t.Fatalf("Refresh() in window = changed %v, %v, want a new token", got != token, err)
+	}
+	if _, err := gojwe.Refresh(j, token, key); !errors.Is(err, gojwe.ErrTokenReplayed) {
+		t.Fatalf("Refresh() of a refreshed token error = %v, want ErrTokenReplayed", err)
+	}
+	if _, err := j.Parse(got, key); err != nil {
+		t.Fatalf("Parse() refreshed error = %v", err)
+	}
+}
+
+func TestRefreshFailedReissueKeepsToken(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	store := gojwe.NewMemoryReplayStore()
+	opts := []gojwe.Option{gojwe.WithTTL(time.Hour), gojwe.WithIssuedAtNow(), gojwe.WithJTI(gojwe.UUIDv4), gojwe.WithReplayProtection(store)}
+	limited := gojwe.New(gojwe.ChaCha20, append(opts, gojwe.WithKeyUsageHardLimit(1))...)
+	token, err := limited.Generate(map[string]any{"sub": "user-1"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := gojwe.Refresh(limited, token, key); !errors.Is(err, gojwe.ErrKeyUsageExceeded) {
+		t.Fatalf("Refresh() error = %v, want ErrKeyUsageExceeded", err)
+	}
+	// The jti was not consumed, so the token can still be refreshed.
+	if _, err := gojwe.Refresh(gojwe.New(gojwe.ChaCha20, opts...), token, key); err != nil {
+		t.Fatalf("Refresh() after a failed reissue error = %v", err)
+	}
+}
+
+func TestRefreshMaxSession(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.XChaCha20, gojwe.WithClock(clock), gojwe.WithTTL(time.Hour), gojwe.WithIssuedAtNow())
+	token, err := j.Generate(map[string]any{"sub": "user-1"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := clock.Now()
+	opts := []gojwe.RefreshOption{gojwe.WithMaxSession(90 * time.Minute)}
+
+	clock.Advance(50 * time.Minute)
+	token, err = gojwe.Refresh(j, token, key, opts...)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	claims, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := start.Add(90 * time.Minute); !claims.ExpiresAt.Equal(want) {
+		t.Fatalf("exp = %v, want capped at %v", claims.ExpiresAt, want)
+	}
+
+	clock.Advance(40 * time.Minute)
+	_, err = gojwe.Refresh(j, token, key, append(opts, gojwe.WithRefreshGrace(time.Minute))...)
+	if !errors.Is(err, gojwe.ErrSessionExpired) {
+		t.Fatalf("Refresh() error = %v, want ErrSessionExpired", err)
+	}
+}
+
+func TestRefreshHPKE(t *testing.T) {
+	j := gojwe.New(gojwe.HPKE)
+	token, err := j.Generate(map[string]any{"sub": "user-1", "exp": 99999999999, "iat": 99999990000}, hpkePublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := gojwe.Refresh(j, token, hpkePrivateKey)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	claims, err := j.Parse(refreshed, hpkePrivateKey)
+	if err != nil || claims["sub"] != "user-1" {
+		t.Fatalf("Parse() = %v, %v", claims, err)
+	}
+}
+
+func TestRefreshRequiresExp(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	token, err := j.Generate(map[string]any{"sub": "user-1"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gojwe.Refresh(j, token, key); !errors.Is(err, gojwe.ErrMissingClaim) {
+		t.Fatalf("Refresh() error = %v, want ErrMissingClaim", err)
+	}
+}
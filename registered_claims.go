@@ -1,9 +1,11 @@
 package gojwe
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/goccy/go-json"
@@ -62,23 +64,44 @@ func (c RegisteredClaims) GetIssuer() (string, error) { return c.Issuer, nil }
 // GetSubject returns the `sub` claim.
 func (c RegisteredClaims) GetSubject() (string, error) { return c.Subject, nil }
 
+// GetID returns the `jti` claim.
+func (c RegisteredClaims) GetID() (string, error) { return c.ID, nil }
+
 // NumericDate represents a JSON numeric date value, as referenced at
 // https://datatracker.ietf.org/doc/html/rfc7519#section-2. It marshals to and
 // from a numeric value counting the seconds since the Unix epoch, which keeps
 // it compatible with the automatic exp/nbf validation.
 type NumericDate struct {
 	time.Time
+
+	// precision is the precision the value marshals at; zero means whole
+	// seconds.
+	precision time.Duration
 }
 
 // NewNumericDate constructs a NumericDate from a standard library time.Time,
 // truncated to whole seconds.
 func NewNumericDate(t time.Time) *NumericDate {
-	return &NumericDate{t.Truncate(time.Second)}
+	return &NumericDate{Time: t.Truncate(time.Second)}
+}
+
+// NewNumericDateWithPrecision constructs a NumericDate truncated to d that
+// marshals with as many fractional digits as d needs, e.g. 1700000000.123 for
+// time.Millisecond. A d of zero or less is the same as NewNumericDate.
+func NewNumericDateWithPrecision(t time.Time, d time.Duration) *NumericDate {
+	if d <= 0 {
+		return NewNumericDate(t)
+	}
+	return &NumericDate{Time: t.Truncate(d), precision: d}
 }
 
 // MarshalJSON implements the json.Marshaler interface, encoding the value as
-// seconds since the Unix epoch.
+// seconds since the Unix epoch, with a fraction when it was built by
+// NewNumericDateWithPrecision with a precision below a second.
 func (d NumericDate) MarshalJSON() ([]byte, error) {
+	if d.precision > 0 {
+		return []byte(formatNumericDate(d.Time, d.precision)), nil
+	}
 	return []byte(strconv.FormatInt(d.Unix(), 10)), nil
 }
 
@@ -88,15 +111,79 @@ func (d *NumericDate) UnmarshalJSON(b []byte) error {
 	if string(b) == "null" {
 		return nil
 	}
-	f, err := strconv.ParseFloat(string(b), 64)
+	t, err := parseNumericDate(string(b))
 	if err != nil {
 		return fmt.Errorf("gojwe: invalid numeric date %q: %w", string(b), err)
 	}
-	sec, frac := math.Modf(f)
-	*d = NumericDate{time.Unix(int64(sec), int64(frac*float64(time.Second)))}
+	*d = NumericDate{Time: t}
 	return nil
 }
 
+// formatNumericDate encodes t as seconds since the Unix epoch truncated to
+// precision, with as many fractional digits as multiples of precision need:
+// none for seconds, 3 for milliseconds, 2 for 250ms, up to 9 for nanoseconds.
+func formatNumericDate(t time.Time, precision time.Duration) json.Number {
+	t = t.Truncate(precision)
+	digits := 9
+	for p := precision; p%10 == 0 && digits > 0; p /= 10 {
+		digits--
+	}
+	if digits == 0 {
+		return json.Number(strconv.FormatInt(t.Unix(), 10))
+	}
+
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	var b []byte
+	if sec < 0 && nsec > 0 {
+		// Unix rounds towards the past; write -0.25 rather than -1.75.
+		sec, nsec = sec+1, int64(time.Second)-nsec
+		if sec == 0 {
+			b = append(b, '-')
+		}
+	}
+	b = strconv.AppendInt(b, sec, 10)
+	frac := strconv.FormatInt(nsec/int64(math.Pow10(9-digits)), 10)
+	b = append(b, '.')
+	for i := len(frac); i < digits; i++ {
+		b = append(b, '0')
+	}
+	return json.Number(append(b, frac...))
+}
+
+// parseNumericDate parses a JSON number of seconds since the Unix epoch.
+// Decimal fractions are read digit by digit, so that they survive exactly
+// where a float64 would round them.
+func parseNumericDate(s string) (time.Time, error) {
+	if whole, frac, ok := strings.Cut(s, "."); !strings.ContainsAny(s, "eE") {
+		sec, err := strconv.ParseInt(whole, 10, 64)
+		var nsec uint64
+		if err == nil && ok {
+			if len(frac) > 9 {
+				frac = frac[:9]
+			}
+			nsec, err = strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		}
+		if err == nil {
+			n := int64(nsec)
+			if strings.HasPrefix(whole, "-") {
+				n = -n
+			}
+			return time.Unix(sec, n), nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return floatTime(f), nil
+}
+
+// floatTime converts float seconds since the Unix epoch to a time.
+func floatTime(f float64) time.Time {
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*float64(time.Second)))
+}
+
 // ClaimStrings is used for the `aud` claim, which per RFC 7519 may be either a
 // single string or an array of strings.
 type ClaimStrings []string
@@ -151,10 +238,17 @@ func (s *ClaimStrings) UnmarshalJSON(b []byte) error {
 // For the built-in algorithms the struct is encrypted directly from its JSON
 // bytes, skipping the map[string]any round-trip.
 func GenerateClaims(j JWE, claims any, key []byte) (string, error) {
-	b, err := json.Marshal(claims)
+	o := defaultOptions()
+	if rc, ok := j.(rawCodec); ok {
+		o = rc.getOptions()
+	}
+	b, err := marshalClaims(claims, o)
 	if err != nil {
 		return "", err
 	}
+	if err := checkGeneratedTags(claims, b); err != nil {
+		return "", err
+	}
 	// Fast path: encrypt the struct's JSON bytes directly.
 	if rc, ok := j.(rawCodec); ok {
 		return rc.generate(b, key)
@@ -167,6 +261,40 @@ func GenerateClaims(j JWE, claims any, key []byte) (string, error) {
 	return j.Generate(payload, key)
 }
 
+// marshalClaims marshals typed claims for GenerateClaims. Under
+// WithTimePrecision the registered time claims of a claimsAccessor are written
+// at the instance precision, since a NumericDate not built with
+// NewNumericDateWithPrecision marshals whole seconds.
+func marshalClaims(claims any, o options) ([]byte, error) {
+	b, err := json.Marshal(claims)
+	if err != nil || o.precision <= 0 {
+		return b, err
+	}
+	acc, ok := claims.(claimsAccessor)
+	if !ok {
+		return b, nil
+	}
+	exp, _ := acc.GetExpirationTime()
+	nbf, _ := acc.GetNotBefore()
+	iat, _ := acc.GetIssuedAt()
+	if exp == nil && nbf == nil && iat == nil {
+		return b, nil
+	}
+
+	m := map[string]any{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber() // keep the other numbers exactly as they were
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	for name, d := range map[string]*NumericDate{"exp": exp, "nbf": nbf, "iat": iat} {
+		if d != nil {
+			m[name] = o.numericDate(d.Time)
+		}
+	}
+	return json.Marshal(m)
+}
+
 // ParseClaims decrypts and validates the token (including exp/nbf handling),
 // then unmarshals its payload into a value of type T. Use it to pull typed data
 // out of a token without manual map access:
@@ -174,7 +302,8 @@ func GenerateClaims(j JWE, claims any, key []byte) (string, error) {
 //	claims, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, key)
 //
 // T may be RegisteredClaims or any struct embedding it alongside your own fields.
-// For the built-in algorithms the payload is unmarshalled straight into T,
+// If T (or *T) implements Validator, its Validate method is called as part of
+// the validation. For the built-in algorithms the payload is unmarshalled straight into T,
 // skipping the map[string]any round-trip.
 func ParseClaims[T any](j JWE, token string, key []byte) (T, error) {
 	var claims T
@@ -190,8 +319,19 @@ func ParseClaims[T any](j JWE, token string, key []byte) (T, error) {
 		if err != nil {
 			return claims, err
 		}
-		err = json.Unmarshal(b, &claims)
-		return claims, err
+		if err = json.Unmarshal(b, &claims); err != nil {
+			return claims, payloadError(err)
+		}
+		if err = checkGeneratedTags(&claims, b); err != nil {
+			return claims, err
+		}
+		// Reported like the fast path, as a claims *TokenError.
+		if v, ok := any(&claims).(Validator); ok {
+			if err = v.Validate(); err != nil {
+				return claims, claimFailure(&ClaimError{Err: err})
+			}
+		}
+		return claims, nil
 	}
 
 	// Fast path: decrypt once, unmarshal straight into T.
@@ -199,10 +339,17 @@ func ParseClaims[T any](j JWE, token string, key []byte) (T, error) {
 	if err != nil {
 		return claims, err
 	}
+	return decodeClaims[T](b, token, rc.getOptions())
+}
+
+// decodeClaims unmarshals a decrypted payload into T and validates the
+// registered claims of token according to opts.
+func decodeClaims[T any](b []byte, token string, opts options) (T, error) {
+	var claims T
 	if err := json.Unmarshal(b, &claims); err != nil {
-		return claims, err
+		return claims, payloadError(err)
 	}
-	if err := validateParsedClaims(claims, b, rc.getOptions()); err != nil {
+	if err := validateParsedClaims(&claims, b, token, opts); err != nil {
 		return claims, err
 	}
 	return claims, nil
@@ -211,69 +358,56 @@ func ParseClaims[T any](j JWE, token string, key []byte) (T, error) {
 // validateParsedClaims enforces the registered claims on an already-parsed
 // value. When the value implements claimsAccessor (i.e. embeds RegisteredClaims)
 // the claims are read straight from it; otherwise they are pulled from the raw
-// JSON with a single lightweight unmarshal.
-func validateParsedClaims(claims any, raw []byte, opts options) error {
-	if !opts.needsValidation() {
+// JSON with a single lightweight unmarshal. A Validator and gojwe struct tags
+// are always checked, even when no other validation is configured. token is
+// the serialized token the claims came from, used for revocation fingerprints.
+func validateParsedClaims(claims any, raw []byte, token string, opts options) error {
+	if _, ok := claims.(Validator); !ok && !opts.needsValidation() && !hasTagRules(claims) {
 		return nil
 	}
-	now := nowFunc()
+	return claimsError(validate(parsedClaimSet(claims, raw, token, opts.precision), opts))
+}
+
+// parsedClaimSet builds the claim view of an already-parsed value, with times
+// read at precision (see toUnixTime).
+func parsedClaimSet(claims any, raw []byte, token string, precision time.Duration) *claimSet {
+	c := &claimSet{token: token, raw: raw, value: claims}
 
 	if acc, ok := claims.(claimsAccessor); ok {
-		if opts.validateTime {
-			if exp, _ := acc.GetExpirationTime(); exp != nil && now.After(exp.Add(opts.leeway)) {
-				return ErrTokenExpired
-			}
-			if nbf, _ := acc.GetNotBefore(); nbf != nil && now.Add(opts.leeway).Before(nbf.Time) {
-				return ErrTokenNotYetValid
-			}
-			if opts.validateIat {
-				if iat, _ := acc.GetIssuedAt(); iat != nil && iat.After(now.Add(opts.leeway)) {
-					return ErrTokenUsedBeforeIssued
-				}
-			}
+		if exp, _ := acc.GetExpirationTime(); exp != nil {
+			c.exp, _ = toUnixTime(exp, precision)
 		}
-		if opts.expectedIss != "" {
-			if iss, _ := acc.GetIssuer(); iss != opts.expectedIss {
-				return ErrInvalidIssuer
-			}
+		if nbf, _ := acc.GetNotBefore(); nbf != nil {
+			c.nbf, _ = toUnixTime(nbf, precision)
 		}
-		if opts.expectedAud != "" {
-			aud, _ := acc.GetAudience()
-			if !audienceContains(aud, opts.expectedAud) {
-				return ErrInvalidAudience
-			}
+		if iat, _ := acc.GetIssuedAt(); iat != nil {
+			c.iat, _ = toUnixTime(iat, precision)
 		}
-		return nil
+		c.iss, _ = acc.GetIssuer()
+		c.sub, _ = acc.GetSubject()
+		c.jti, _ = acc.GetID()
+		aud, _ := acc.GetAudience()
+		c.aud = aud
+		return c
 	}
 
 	// Fallback: the struct does not expose the registered-claim getters, so
 	// pull the standard claims out of the raw JSON with one small unmarshal.
 	var tc struct {
-		Exp *float64     `json:"exp"`
-		Nbf *float64     `json:"nbf"`
-		Iat *float64     `json:"iat"`
+		Exp json.Number  `json:"exp"`
+		Nbf json.Number  `json:"nbf"`
+		Iat json.Number  `json:"iat"`
 		Iss string       `json:"iss"`
+		Sub string       `json:"sub"`
 		Aud ClaimStrings `json:"aud"`
+		Jti string       `json:"jti"`
 	}
 	if err := json.Unmarshal(raw, &tc); err != nil {
-		return nil // no recognizable registered claims to enforce
-	}
-	if opts.validateTime {
-		if tc.Exp != nil && now.After(time.Unix(int64(*tc.Exp), 0).Add(opts.leeway)) {
-			return ErrTokenExpired
-		}
-		if tc.Nbf != nil && now.Add(opts.leeway).Before(time.Unix(int64(*tc.Nbf), 0)) {
-			return ErrTokenNotYetValid
-		}
-		if opts.validateIat && tc.Iat != nil && time.Unix(int64(*tc.Iat), 0).After(now.Add(opts.leeway)) {
-			return ErrTokenUsedBeforeIssued
-		}
-	}
-	if opts.expectedIss != "" && tc.Iss != opts.expectedIss {
-		return ErrInvalidIssuer
+		return c // no recognizable registered claims
 	}
-	if opts.expectedAud != "" && !audienceContains(tc.Aud, opts.expectedAud) {
-		return ErrInvalidAudience
-	}
-	return nil
+	c.exp, _ = toUnixTime(tc.Exp, precision)
+	c.nbf, _ = toUnixTime(tc.Nbf, precision)
+	c.iat, _ = toUnixTime(tc.Iat, precision)
+	c.iss, c.sub, c.aud, c.jti = tc.Iss, tc.Sub, tc.Aud, tc.Jti
+	return c
 }
@@ -11,6 +11,7 @@ import (
 func TestRegisteredClaimsRoundTrip(t *testing.T) {
 	key := gojwe.MustGenerateKey()
 	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
 		j := gojwe.New(alg)
 
 		want := gojwe.RegisteredClaims{
@@ -22,12 +23,12 @@ func TestRegisteredClaimsRoundTrip(t *testing.T) {
 			ExpiresAt: gojwe.NewNumericDate(time.Now().Add(time.Hour)),
 		}
 
-		token, err := gojwe.GenerateClaims(j, want, key)
+		token, err := gojwe.GenerateClaims(j, want, genKey)
 		if err != nil {
 			t.Fatalf("[%s] GenerateClaims() error = %v", alg, err)
 		}
 
-		got, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, key)
+		got, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey)
 		if err != nil {
 			t.Fatalf("[%s] ParseClaims() error = %v", alg, err)
 		}
@@ -0,0 +1,126 @@
+package gojwe
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// ReplayStore remembers the "jti" of tokens that have already been accepted.
+// Implement it on top of a shared cache (e.g. Redis SET NX with an expiry) when
+// several instances verify tokens; NewMemoryReplayStore provides a
+// process-local version.
+type ReplayStore interface {
+	// CheckAndStore atomically records jti as used until the given time and
+	// reports whether it had already been recorded. A zero until means the
+	// token never expires and the jti must be kept forever. now is the
+	// validation time (see WithClock); entries whose until is not after now
+	// have expired.
+	CheckAndStore(jti string, until, now time.Time) (replayed bool, err error)
+}
+
+// checkReplay rejects tokens whose jti was already seen and records new ones
+// until their expiry plus leeway, after which they are rejected as expired
+// anyway.
+func checkReplay(c *claimSet, opts options, v *validation) error {
+	if c.jti == "" {
+		v.fail(&ClaimError{Claim: "jti", Err: ErrMissingClaim})
+		return nil
+	}
+	var until time.Time
+	if !c.exp.IsZero() {
+		until = c.exp.Add(opts.leeway)
+	}
+	replayed, err := opts.replayStore.CheckAndStore(c.jti, until, v.now)
+	if err != nil {
+		return err
+	}
+	if replayed {
+		v.fail(&ClaimError{Claim: "jti", Err: ErrTokenReplayed})
+	}
+	return nil
+}
+
+// replayShards is the number of independently locked shards of a
+// MemoryReplayStore, so concurrent verifications rarely contend.
+const replayShards = 64
+
+// replaySweepEvery is how many inserts a shard accepts between sweeps of its
+// expired entries.
+const replaySweepEvery = 1024
+
+// MemoryReplayStore is an in-memory ReplayStore whose entries expire with their
+// tokens. It is sharded by jti to keep lock contention low and is safe for
+// concurrent use.
+type MemoryReplayStore struct {
+	seed   maphash.Seed
+	shards [replayShards]replayShard
+}
+
+type replayShard struct {
+	mu      sync.Mutex
+	until   map[string]time.Time
+	inserts int
+}
+
+// NewMemoryReplayStore returns an empty in-memory replay store.
+func NewMemoryReplayStore() *MemoryReplayStore {
+	s := &MemoryReplayStore{seed: maphash.MakeSeed()}
+	for i := range s.shards {
+		s.shards[i].until = map[string]time.Time{}
+	}
+	return s
+}
+
+// CheckAndStore implements ReplayStore.
+func (s *MemoryReplayStore) CheckAndStore(jti string, until, now time.Time) (bool, error) {
+	shard := &s.shards[maphash.String(s.seed, jti)%replayShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if prev, ok := shard.until[jti]; ok && (prev.IsZero() || now.Before(prev)) {
+		return true, nil
+	}
+	shard.until[jti] = until
+
+	shard.inserts++
+	if shard.inserts >= replaySweepEvery {
+		shard.inserts = 0
+		shard.sweep(now)
+	}
+	return false, nil
+}
+
+// Len returns the number of recorded jti values, including expired ones that
+// have not been swept yet.
+func (s *MemoryReplayStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		n += len(shard.until)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// Purge removes every entry expired at now. Expired entries are also swept
+// periodically as new tokens are recorded, so calling it is optional.
+func (s *MemoryReplayStore) Purge(now time.Time) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		shard.sweep(now)
+		shard.mu.Unlock()
+	}
+}
+
+// sweep deletes expired entries; the caller holds the shard lock.
+func (s *replayShard) sweep(now time.Time) {
+	for jti, until := range s.until {
+		if !until.IsZero() && !now.Before(until) {
+			delete(s.until, jti)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gojwe_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestReplayProtection(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithReplayProtection(gojwe.NewMemoryReplayStore()))
+		token, _ := j.Generate(map[string]any{
+			"jti": "token-1",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}, genKey)
+
+		if _, err := j.Parse(token, parseKey); err != nil {
+			t.Fatalf("[%s] first Parse() error = %v", alg, err)
+		}
+		if _, err := j.Parse(token, parseKey); !errors.Is(err, gojwe.ErrTokenReplayed) {
+			t.Fatalf("[%s] second Parse() error = %v, want ErrTokenReplayed", alg, err)
+		}
+		if j.Verify(token, parseKey) {
+			t.Fatalf("[%s] Verify() of a replayed token = true, want false", alg)
+		}
+
+		noJTI, _ := j.Generate(map[string]any{"sub": "x"}, genKey)
+		if _, err := j.Parse(noJTI, parseKey); !errors.Is(err, gojwe.ErrMissingClaim) {
+			t.Fatalf("[%s] Parse() without jti error = %v, want ErrMissingClaim", alg, err)
+		}
+	}
+}
+
+func TestReplayProtectionParseClaims(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithReplayProtection(gojwe.NewMemoryReplayStore()))
+	token, _ := gojwe.GenerateClaims(j, gojwe.RegisteredClaims{
+		ID:        "token-1",
+		ExpiresAt: gojwe.NewNumericDate(time.Now().Add(time.Hour)),
+	}, key)
+
+	if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, key); err != nil {
+		t.Fatalf("first ParseClaims() error = %v", err)
+	}
+	// The map and typed paths share the store.
+	if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrTokenReplayed) {
+		t.Fatalf("Parse() after ParseClaims() error = %v, want ErrTokenReplayed", err)
+	}
+
+	// Structs without the registered-claim getters use the raw JSON.
+	type bare struct {
+		ID string `json:"jti"`
+	}
+	if _, err := gojwe.ParseClaims[bare](j, token, key); !errors.Is(err, gojwe.ErrTokenReplayed) {
+		t.Fatalf("ParseClaims[bare]() error = %v, want ErrTokenReplayed", err)
+	}
+}
+
+func TestReplayProtectionRecordsOnlyValidTokens(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	store := gojwe.NewMemoryReplayStore()
+	token, _ := gojwe.New(gojwe.XChaCha20).Generate(map[string]any{
+		"jti": "token-1",
+		"aud": "api",
+	}, key)
+
+	wrongAud := gojwe.New(gojwe.XChaCha20, gojwe.WithReplayProtection(store), gojwe.WithAudience("web"))
+	if _, err := wrongAud.Parse(token, key); !errors.Is(err, gojwe.ErrInvalidAudience) {
+		t.Fatalf("Parse() error = %v, want ErrInvalidAudience", err)
+	}
+	rightAud := gojwe.New(gojwe.XChaCha20, gojwe.WithReplayProtection(store), gojwe.WithAudience("api"))
+	if _, err := rightAud.Parse(token, key); err != nil {
+		t.Fatalf("Parse() after a rejected attempt error = %v", err)
+	}
+}
+
+func TestMemoryReplayStore(t *testing.T) {
+	s := gojwe.NewMemoryReplayStore()
+	now := time.Now()
+
+	// Entries that already expired do not count as replays.
+	past := now.Add(-time.Second)
+	if replayed, _ := s.CheckAndStore("old", past, now); replayed {
+		t.Fatal("CheckAndStore() first call replayed = true")
+	}
+	if replayed, _ := s.CheckAndStore("old", past, now); replayed {
+		t.Fatal("CheckAndStore() of an expired entry replayed = true")
+	}
+
+	// A zero expiry is kept forever.
+	_, _ = s.CheckAndStore("forever", time.Time{}, now)
+	if replayed, _ := s.CheckAndStore("forever", time.Time{}, now); !replayed {
+		t.Fatal("CheckAndStore() of a non-expiring entry replayed = false")
+	}
+
+	for i := 0; i < 100; i++ {
+		_, _ = s.CheckAndStore(fmt.Sprint("jti-", i), now.Add(time.Hour), now)
+	}
+	if got := s.Len(); got != 102 {
+		t.Fatalf("Len() = %d, want 102", got)
+	}
+	s.Purge(now)
+	if got := s.Len(); got != 101 {
+		t.Fatalf("Len() after Purge() = %d, want 101", got)
+	}
+}
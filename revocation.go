@@ -0,0 +1,177 @@
+package gojwe
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Revoker decides whether an individual token has been revoked before its
+// expiry, e.g. a leaked support token. It is consulted by Parse/Verify and
+// ParseClaims after decryption when configured with WithRevoker.
+type Revoker interface {
+	// IsRevoked reports whether the token with the given "jti" (empty when the
+	// token has none) or Fingerprint has been revoked at now, the validation
+	// time (see WithClock).
+	IsRevoked(jti, fingerprint string, now time.Time) (bool, error)
+}
+
+// Fingerprint returns a stable identifier for a serialized token, for revoking
+// tokens that carry no "jti". It is the base64url SHA-256 of the token, so the
+// revocation list never holds usable tokens.
+func Fingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// revocationList is a set of revoked identifiers (jti values or fingerprints)
+// mapped to the time after which the entry can be forgotten because the token
+// has expired anyway. A zero time keeps the entry forever.
+type revocationList map[string]time.Time
+
+func (l revocationList) contains(id string, now time.Time) bool {
+	until, ok := l[id]
+	return ok && (until.IsZero() || now.Before(until))
+}
+
+// sweep deletes expired entries.
+func (l revocationList) sweep(now time.Time) {
+	for id, until := range l {
+		if !until.IsZero() && !now.Before(until) {
+			delete(l, id)
+		}
+	}
+}
+
+// revokerSweepEvery is how many revocations a MemoryRevoker or
+// MemoryFamilyStore accepts between sweeps of its expired entries.
+const revokerSweepEvery = 1024
+
+// MemoryRevoker is an in-memory Revoker. Entries are dropped once the revoked
+// token has expired. It is safe for concurrent use.
+type MemoryRevoker struct {
+	mu      sync.RWMutex
+	list    revocationList
+	revokes int
+
+	// now is the latest validation time seen by IsRevoked, for sweeps.
+	now time.Time
+}
+
+// NewMemoryRevoker returns an empty in-memory revoker.
+func NewMemoryRevoker() *MemoryRevoker {
+	return &MemoryRevoker{list: revocationList{}}
+}
+
+// Revoke revokes the token identified by id, a "jti" value or a Fingerprint.
+// Pass the token's expiry (plus leeway) as until so the entry can be garbage
+// collected afterwards, or the zero time to keep it forever.
+func (r *MemoryRevoker) Revoke(id string, until time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.list[id] = until
+
+	r.revokes++
+	if r.revokes >= revokerSweepEvery && !r.now.IsZero() {
+		r.revokes = 0
+		r.list.sweep(r.now)
+	}
+}
+
+// RevokeToken revokes a serialized token by its Fingerprint; see Revoke.
+func (r *MemoryRevoker) RevokeToken(token string, until time.Time) {
+	r.Revoke(Fingerprint(token), until)
+}
+
+// IsRevoked implements Revoker.
+func (r *MemoryRevoker) IsRevoked(jti, fingerprint string, now time.Time) (bool, error) {
+	r.mu.RLock()
+	revoked := (jti != "" && r.list.contains(jti, now)) || r.list.contains(fingerprint, now)
+	seen := !now.After(r.now)
+	r.mu.RUnlock()
+
+	if !seen {
+		r.mu.Lock()
+		if now.After(r.now) {
+			r.now = now
+		}
+		r.mu.Unlock()
+	}
+	return revoked, nil
+}
+
+// Purge removes every entry expired at now. Expired entries are also swept
+// periodically as new revocations are added, so calling it is optional.
+func (r *MemoryRevoker) Purge(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.list.sweep(now)
+}
+
+// FileRevoker is a Revoker backed by a revocation list on disk, reloaded
+// automatically when the file changes. Each line holds a "jti" value or a
+// Fingerprint, optionally followed by the Unix time after which the entry
+// expires; blank lines and lines starting with '#' are ignored:
+//
+//	# leaked support token, valid until 2026-01-01
+//	support-7f3a 1767225600
+//	Yx1cD0vN1q4kJ8tWb2r0m9Sx3e7hA5uL6fGzPqRsT0c
+//
+// If the file cannot be reloaded, IsRevoked returns the error so that tokens
+// are rejected rather than accepted with a stale list.
+type FileRevoker struct {
+	file *watchedFile
+	mu   sync.RWMutex
+	list revocationList
+}
+
+// NewFileRevoker loads the revocation list at path. The file is checked for
+// changes at most once per interval, during IsRevoked.
+func NewFileRevoker(path string, interval time.Duration) (*FileRevoker, error) {
+	r := &FileRevoker{file: newWatchedFile(path, interval)}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the revocation list from disk immediately.
+func (r *FileRevoker) Reload() error {
+	list := revocationList{}
+	err := r.file.load(func(fields []string) error {
+		if len(fields) > 2 {
+			return fmt.Errorf("want \"<jti-or-fingerprint> [unix-time]\", got %d fields", len(fields))
+		}
+		until, err := parseUnixField(fields, 1)
+		if err != nil {
+			return err
+		}
+		list[fields[0]] = until
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.list = list
+	r.mu.Unlock()
+	return nil
+}
+
+// IsRevoked implements Revoker.
+func (r *FileRevoker) IsRevoked(jti, fingerprint string, now time.Time) (bool, error) {
+	if changed, err := r.file.changed(); err != nil {
+		return false, err
+	} else if changed {
+		if err := r.Reload(); err != nil {
+			return false, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return (jti != "" && r.list.contains(jti, now)) || r.list.contains(fingerprint, now), nil
+}
@@ -0,0 +1,159 @@
+package gojwe_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestMemoryRevoker(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		revoker := gojwe.NewMemoryRevoker()
+		j := gojwe.New(alg, gojwe.WithRevoker(revoker))
+
+		withID, _ := j.Generate(map[string]any{"jti": "support-1"}, genKey)
+		withoutID, _ := j.Generate(map[string]any{"sub": "user-1"}, genKey)
+		other, _ := j.Generate(map[string]any{"jti": "support-2"}, genKey)
+
+		revoker.Revoke("support-1", time.Now().Add(time.Hour))
+		revoker.RevokeToken(withoutID, time.Time{})
+
+		if _, err := j.Parse(withID, parseKey); !errors.Is(err, gojwe.ErrTokenRevoked) {
+			t.Fatalf("[%s] Parse() by jti error = %v, want ErrTokenRevoked", alg, err)
+		}
+		if _, err := j.Parse(withoutID, parseKey); !errors.Is(err, gojwe.ErrTokenRevoked) {
+			t.Fatalf("[%s] Parse() by fingerprint error = %v, want ErrTokenRevoked", alg, err)
+		}
+		if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, withID, parseKey); !errors.Is(err, gojwe.ErrTokenRevoked) {
+			t.Fatalf("[%s] ParseClaims() error = %v, want ErrTokenRevoked", alg, err)
+		}
+		if _, err := j.Parse(other, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() of a non-revoked token error = %v", alg, err)
+		}
+	}
+}
+
+func TestMemoryRevokerExpiry(t *testing.T) {
+	revoker := gojwe.NewMemoryRevoker()
+	now := time.Now()
+	revoker.Revoke("old", now.Add(-time.Second))
+	if revoked, _ := revoker.IsRevoked("old", "", now); revoked {
+		t.Fatal("IsRevoked() of an expired entry = true, want false")
+	}
+	revoker.Purge(now)
+	revoker.Revoke("new", now.Add(time.Hour))
+	if revoked, _ := revoker.IsRevoked("new", "", now); !revoked {
+		t.Fatal("IsRevoked() = false, want true")
+	}
+}
+
+func TestParseBytesRevoked(t *testing.T) {
+	raw := gojwe.MustGenerateKey()
+	revoker := gojwe.NewMemoryRevoker()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithRevoker(revoker))
+	key, _ := gojwe.PrepareKey(gojwe.ChaCha20, raw)
+
+	token, _ := j.Generate(map[string]any{"sub": "x"}, raw)
+	revoker.RevokeToken(token, time.Time{})
+	if _, err := gojwe.ParseBytes(j, []byte(token), key); !errors.Is(err, gojwe.ErrTokenRevoked) {
+		t.Fatalf("ParseBytes() error = %v, want ErrTokenRevoked", err)
+	}
+}
+
+func TestFileRevoker(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j0 := gojwe.New(gojwe.XChaCha20)
+	leaked, _ := j0.Generate(map[string]any{"sub": "support"}, key)
+	fresh, _ := j0.Generate(map[string]any{"jti": "later"}, key)
+
+	path := filepath.Join(t.TempDir(), "revoked.txt")
+	writeFile(t, path, "# revoked tokens\n\n"+gojwe.Fingerprint(leaked)+"\nexpired-jti 1\n")
+
+	revoker, err := gojwe.NewFileRevoker(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileRevoker() error = %v", err)
+	}
+	j := gojwe.New(gojwe.XChaCha20, gojwe.WithRevoker(revoker))
+	if _, err := j.Parse(leaked, key); !errors.Is(err, gojwe.ErrTokenRevoked) {
+		t.Fatalf("Parse() error = %v, want ErrTokenRevoked", err)
+	}
+	if revoked, _ := revoker.IsRevoked("expired-jti", "", time.Now()); revoked {
+		t.Fatal("IsRevoked() of an expired entry = true, want false")
+	}
+	if _, err := j.Parse(fresh, key); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// Changes on disk are picked up without restarting.
+	writeFile(t, path, "later\n")
+	if _, err := j.Parse(fresh, key); !errors.Is(err, gojwe.ErrTokenRevoked) {
+		t.Fatalf("Parse() after reload error = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := j.Parse(leaked, key); err != nil {
+		t.Fatalf("Parse() of an un-revoked token error = %v", err)
+	}
+
+	// A list that cannot be read fails closed.
+	_ = os.Remove(path)
+	if _, err := j.Parse(fresh, key); err == nil {
+		t.Fatal("Parse() with a missing revocation list succeeded, want error")
+	}
+}
+
+func TestFileRevokerInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revoked.txt")
+	for _, content := range []string{"jti not-a-time\n", "jti 1 2 3\n"} {
+		writeFile(t, path, content)
+		if _, err := gojwe.NewFileRevoker(path, time.Minute); err == nil {
+			t.Fatalf("NewFileRevoker() with line %q succeeded, want error", content)
+		}
+	}
+}
+
+func TestFileRevokerFailedReloadFailsClosed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revoked.txt")
+	writeFile(t, path, "old-jti\n")
+	revoker, err := gojwe.NewFileRevoker(path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileRevoker() error = %v", err)
+	}
+
+	// Later checks within the interval keep failing instead of using the
+	// stale list.
+	writeFile(t, path, "new-jti not-a-time\n")
+	if err := revoker.Reload(); err == nil {
+		t.Fatal("Reload() of an invalid file succeeded, want error")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := revoker.IsRevoked("other-jti", "", time.Now()); err == nil {
+			t.Fatalf("IsRevoked() #%d after a failed reload succeeded, want error", i)
+		}
+	}
+
+	writeFile(t, path, "new-jti\n")
+	if err := revoker.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if revoked, err := revoker.IsRevoked("new-jti", "", time.Now()); err != nil || !revoked {
+		t.Fatalf("IsRevoked() after a successful reload = %v, %v, want true", revoked, err)
+	}
+}
+
+// writeFile replaces the file at path, making sure its modification time
+// changes even on coarse-grained file systems.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
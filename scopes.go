@@ -0,0 +1,121 @@
+package gojwe
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// Scopes is used for the OAuth 2.0 "scope" claim, which per RFC 8693 §4.2 is
+// a single string of space-delimited scopes:
+//
+//	type AccessClaims struct {
+//	    gojwe.RegisteredClaims
+//	    Scope gojwe.Scopes `json:"scope,omitempty"`
+//	    Roles []string     `json:"roles,omitempty"`
+//	}
+type Scopes []string
+
+// Has reports whether scope is one of s.
+func (s Scopes) Has(scope string) bool {
+	return containsString(s, scope)
+}
+
+// MarshalJSON encodes the scopes as one space-delimited string.
+func (s Scopes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.Join(s, " "))
+}
+
+// UnmarshalJSON accepts a space-delimited string, and also an array of
+// strings as issued by some providers.
+func (s *Scopes) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var raw any
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	scopes, ok := claimValues(raw, true)
+	if !ok {
+		return fmt.Errorf("gojwe: invalid scope type %T", raw)
+	}
+	*s = scopes
+	return nil
+}
+
+// HasScope reports whether the "scope" claim of decoded claims contains scope.
+func HasScope(claims map[string]any, scope string) bool {
+	scopes, _ := claimValues(claims["scope"], true)
+	return containsString(scopes, scope)
+}
+
+// HasRole reports whether the "roles" claim of decoded claims contains role.
+func HasRole(claims map[string]any, role string) bool {
+	roles, _ := claimValues(claims["roles"], false)
+	return containsString(roles, role)
+}
+
+// HasAnyRole reports whether the "roles" claim of decoded claims contains at
+// least one of roles.
+func HasAnyRole(claims map[string]any, roles ...string) bool {
+	have, _ := claimValues(claims["roles"], false)
+	for _, role := range roles {
+		if containsString(have, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimValues returns the strings of a claim holding a string array or a
+// single string. With split, a single string is split on spaces.
+func claimValues(v any, split bool) ([]string, bool) {
+	if s, ok := v.(string); ok {
+		if split {
+			return strings.Fields(s), true
+		}
+		return []string{s}, true
+	}
+	return stringList(v)
+}
+
+// checkGrants enforces WithRequiredScopes and WithRequiredRoles: the token
+// must hold every required scope and role.
+func checkGrants(c *claimSet, opts options, v *validation) error {
+	if len(opts.requiredScopes) > 0 {
+		raw, err := c.get("scope")
+		if err != nil {
+			return err
+		}
+		have, _ := claimValues(raw, true)
+		if requireAll(v, "scope", Scopes(have), opts.requiredScopes) {
+			return nil
+		}
+	}
+	if len(opts.requiredRoles) > 0 {
+		raw, err := c.get("roles")
+		if err != nil {
+			return err
+		}
+		have, _ := claimValues(raw, false)
+		requireAll(v, "roles", have, opts.requiredRoles)
+	}
+	return nil
+}
+
+// requireAll reports every value of want missing from the claim values have,
+// and whether validation must stop.
+func requireAll(v *validation, claim string, have, want []string) bool {
+	var actual any
+	if len(have) > 0 {
+		actual = have
+	}
+	for _, w := range want {
+		if !containsString(have, w) && v.fail(&ClaimError{Claim: claim, Expected: w, Actual: actual, Err: ErrInsufficientScope}) {
+			return true
+		}
+	}
+	return false
+}
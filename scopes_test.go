@@ -0,0 +1,87 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/prongbang/gojwe"
+)
+
+type accessClaims struct {
+	gojwe.RegisteredClaims
+	Scope gojwe.Scopes `json:"scope,omitempty"`
+	Roles []string     `json:"roles,omitempty"`
+}
+
+func TestScopesJSON(t *testing.T) {
+	b, err := json.Marshal(gojwe.Scopes{"read", "write"})
+	if err != nil || string(b) != `"read write"` {
+		t.Fatalf("Marshal() = %s, %v, want \"read write\"", b, err)
+	}
+
+	for _, in := range []string{`"read  write"`, `["read","write"]`} {
+		var s gojwe.Scopes
+		if err := json.Unmarshal([]byte(in), &s); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", in, err)
+		}
+		if len(s) != 2 || !s.Has("read") || !s.Has("write") || s.Has("admin") {
+			t.Fatalf("Unmarshal(%s) = %q", in, s)
+		}
+	}
+
+	var s gojwe.Scopes
+	if err := json.Unmarshal([]byte(`42`), &s); err == nil {
+		t.Fatal("Unmarshal(42) succeeded, want error")
+	}
+}
+
+func TestScopeHelpers(t *testing.T) {
+	claims := map[string]any{"scope": "read write", "roles": []any{"editor", "viewer"}}
+	if !gojwe.HasScope(claims, "write") || gojwe.HasScope(claims, "admin") {
+		t.Fatal("HasScope() mismatch")
+	}
+	if !gojwe.HasRole(claims, "viewer") || gojwe.HasRole(claims, "admin") {
+		t.Fatal("HasRole() mismatch")
+	}
+	if !gojwe.HasAnyRole(claims, "admin", "editor") || gojwe.HasAnyRole(claims, "admin") {
+		t.Fatal("HasAnyRole() mismatch")
+	}
+	if gojwe.HasScope(map[string]any{}, "read") {
+		t.Fatal("HasScope() without scope claim = true")
+	}
+}
+
+func TestWithRequiredScopesAndRoles(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	tests := []struct {
+		name   string
+		claims accessClaims
+		want   error
+	}{
+		{"granted", accessClaims{Scope: gojwe.Scopes{"orders:read", "orders:write"}, Roles: []string{"staff"}}, nil},
+		{"missing scope", accessClaims{Scope: gojwe.Scopes{"orders:read"}, Roles: []string{"staff"}}, gojwe.ErrInsufficientScope},
+		{"missing role", accessClaims{Scope: gojwe.Scopes{"orders:read", "orders:write"}, Roles: []string{"guest"}}, gojwe.ErrInsufficientScope},
+		{"no claims", accessClaims{}, gojwe.ErrInsufficientScope},
+	}
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithRequiredScopes("orders:read", "orders:write"), gojwe.WithRequiredRoles("staff"))
+		for _, tt := range tests {
+			token, err := gojwe.GenerateClaims(j, tt.claims, genKey)
+			if err != nil {
+				t.Fatalf("[%s] GenerateClaims() error = %v", alg, err)
+			}
+			if _, err := j.Parse(token, parseKey); !errors.Is(err, tt.want) {
+				t.Fatalf("[%s] %s: Parse() error = %v, want %v", alg, tt.name, err, tt.want)
+			}
+			claims, err := gojwe.ParseClaims[accessClaims](j, token, parseKey)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("[%s] %s: ParseClaims() error = %v, want %v", alg, tt.name, err, tt.want)
+			}
+			if err == nil && !claims.Scope.Has("orders:write") {
+				t.Fatalf("[%s] ParseClaims() scope = %q", alg, claims.Scope)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package gojwe
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionEpochStore records, per subject, the time before which every token is
+// invalid, e.g. the moment of a password reset. It is consulted by Parse/Verify
+// and ParseClaims after decryption when configured with WithSessionEpochs, and
+// lets a single entry invalidate all older tokens of a "sub" without tracking
+// each "jti".
+type SessionEpochStore interface {
+	// ValidAfter returns the time tokens for sub must have been issued at or
+	// after, or the zero time when the subject has no epoch.
+	ValidAfter(sub string) (time.Time, error)
+}
+
+// checkSessionEpoch rejects tokens whose "iat" lies before the epoch of their
+// subject. The epoch is truncated to the precision of "iat" (whole seconds, or
+// WithTimePrecision), so that tokens issued right after it stay valid. A token
+// without "iat" cannot prove it is recent and is rejected as soon as its
+// subject has an epoch; tokens without "sub" are not affected.
+func checkSessionEpoch(c *claimSet, opts options, v *validation) error {
+	if opts.sessionEpochs == nil || c.sub == "" {
+		return nil
+	}
+	after, err := opts.sessionEpochs.ValidAfter(c.sub)
+	if err != nil {
+		return err
+	}
+	after = opts.truncate(after)
+	switch {
+	case after.IsZero():
+	case c.iat.IsZero():
+		v.fail(&ClaimError{Claim: "iat", Expected: after, Err: ErrSessionInvalidated})
+	case c.iat.Before(after):
+		v.fail(&ClaimError{Claim: "iat", Expected: after, Actual: c.iat, Delta: after.Sub(c.iat), Err: ErrSessionInvalidated})
+	}
+	return nil
+}
+
+// MemorySessionEpochStore is an in-memory SessionEpochStore. It is safe for
+// concurrent use.
+type MemorySessionEpochStore struct {
+	mu     sync.RWMutex
+	epochs map[string]time.Time
+}
+
+// NewMemorySessionEpochStore returns an empty in-memory epoch store.
+func NewMemorySessionEpochStore() *MemorySessionEpochStore {
+	return &MemorySessionEpochStore{epochs: map[string]time.Time{}}
+}
+
+// Invalidate rejects every token of sub issued before at ("log out
+// everywhere"). at is kept at full resolution and compared at the precision of
+// the validating instance: by default a reset takes effect from its whole
+// second, and under WithTimePrecision(time.Millisecond) from its millisecond.
+// An earlier at than the current epoch is ignored.
+func (s *MemorySessionEpochStore) Invalidate(sub string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at.After(s.epochs[sub]) {
+		s.epochs[sub] = at
+	}
+}
+
+// Forget removes the epoch of sub, e.g. once its last invalidated token has
+// expired.
+func (s *MemorySessionEpochStore) Forget(sub string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.epochs, sub)
+}
+
+// ValidAfter implements SessionEpochStore.
+func (s *MemorySessionEpochStore) ValidAfter(sub string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.epochs[sub], nil
+}
+
+// FileSessionEpochStore is a SessionEpochStore backed by a file on disk,
+// reloaded automatically when it changes. Each line holds a subject followed by
+// the Unix time its tokens must have been issued at or after; blank lines and
+// lines starting with '#' are ignored. When a subject appears more than once
+// the latest time wins:
+//
+//	# password reset
+//	user-42 1767225600
+//
+// If the file cannot be reloaded, ValidAfter returns the error so that tokens
+// are rejected rather than accepted with stale epochs.
+type FileSessionEpochStore struct {
+	file   *watchedFile
+	mu     sync.RWMutex
+	epochs map[string]time.Time
+}
+
+// NewFileSessionEpochStore loads the epochs at path. The file is checked for
+// changes at most once per interval, during ValidAfter.
+func NewFileSessionEpochStore(path string, interval time.Duration) (*FileSessionEpochStore, error) {
+	s := &FileSessionEpochStore{file: newWatchedFile(path, interval)}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the epochs from disk immediately.
+func (s *FileSessionEpochStore) Reload() error {
+	epochs := map[string]time.Time{}
+	err := s.file.load(func(fields []string) error {
+		if len(fields) != 2 {
+			return fmt.Errorf("want \"<sub> <unix-time>\", got %d fields", len(fields))
+		}
+		at, err := parseUnixField(fields, 1)
+		if err != nil {
+			return err
+		}
+		if at.After(epochs[fields[0]]) {
+			epochs[fields[0]] = at
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.epochs = epochs
+	s.mu.Unlock()
+	return nil
+}
+
+// ValidAfter implements SessionEpochStore.
+func (s *FileSessionEpochStore) ValidAfter(sub string) (time.Time, error) {
+	if changed, err := s.file.changed(); err != nil {
+		return time.Time{}, err
+	} else if changed {
+		if err := s.Reload(); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.epochs[sub], nil
+}
@@ -0,0 +1,148 @@
+package gojwe_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestSessionEpochs(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	now := time.Now()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		store := gojwe.NewMemorySessionEpochStore()
+		j := gojwe.New(alg, gojwe.WithSessionEpochs(store))
+
+		old, _ := j.Generate(map[string]any{"sub": "user-1", "iat": now.Add(-time.Hour).Unix()}, genKey)
+		noIat, _ := j.Generate(map[string]any{"sub": "user-1"}, genKey)
+		other, _ := j.Generate(map[string]any{"sub": "user-2", "iat": now.Add(-time.Hour).Unix()}, genKey)
+
+		if _, err := j.Parse(old, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() before invalidation error = %v", alg, err)
+		}
+
+		// Password reset: everything issued so far is invalid.
+		store.Invalidate("user-1", now)
+		fresh, _ := j.Generate(map[string]any{"sub": "user-1", "iat": now.Unix()}, genKey)
+
+		if _, err := j.Parse(old, parseKey); !errors.Is(err, gojwe.ErrSessionInvalidated) {
+			t.Fatalf("[%s] Parse() error = %v, want ErrSessionInvalidated", alg, err)
+		}
+		if _, err := j.Parse(noIat, parseKey); !errors.Is(err, gojwe.ErrSessionInvalidated) {
+			t.Fatalf("[%s] Parse() without iat error = %v, want ErrSessionInvalidated", alg, err)
+		}
+		if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, old, parseKey); !errors.Is(err, gojwe.ErrSessionInvalidated) {
+			t.Fatalf("[%s] ParseClaims() error = %v, want ErrSessionInvalidated", alg, err)
+		}
+		if _, err := j.Parse(fresh, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() of a token issued after the reset error = %v", alg, err)
+		}
+		if _, err := j.Parse(other, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() of another subject error = %v", alg, err)
+		}
+
+		store.Forget("user-1")
+		if _, err := j.Parse(old, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() after Forget error = %v", alg, err)
+		}
+	}
+}
+
+func TestSessionEpochPrecision(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 300*int64(time.Millisecond)))
+	store := gojwe.NewMemorySessionEpochStore()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(clock), gojwe.WithIssuedAtNow(),
+		gojwe.WithSessionEpochs(store), gojwe.WithTimePrecision(time.Millisecond))
+	whole := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(clock), gojwe.WithSessionEpochs(store))
+
+	before, _ := j.Generate(map[string]any{"sub": "user-1"}, key)
+	clock.Advance(200 * time.Millisecond)
+	store.Invalidate("user-1", clock.Now())
+	after, _ := j.Generate(map[string]any{"sub": "user-1"}, key)
+
+	if _, err := j.Parse(before, key); !errors.Is(err, gojwe.ErrSessionInvalidated) {
+		t.Fatalf("Parse() of a token issued 200ms before the reset error = %v, want ErrSessionInvalidated", err)
+	}
+	if _, err := j.Parse(after, key); err != nil {
+		t.Fatalf("Parse() of a token issued at the reset error = %v", err)
+	}
+	// At whole-second precision the reset takes effect from its second.
+	if _, err := whole.Parse(before, key); err != nil {
+		t.Fatalf("Parse() at whole seconds error = %v", err)
+	}
+}
+
+func TestMemorySessionEpochStoreKeepsLatest(t *testing.T) {
+	store := gojwe.NewMemorySessionEpochStore()
+	later := time.Unix(2000, 0)
+	store.Invalidate("user-1", later)
+	store.Invalidate("user-1", time.Unix(1000, 0))
+	if got, _ := store.ValidAfter("user-1"); !got.Equal(later) {
+		t.Fatalf("ValidAfter() = %v, want %v", got, later)
+	}
+}
+
+func TestFileSessionEpochStore(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j0 := gojwe.New(gojwe.ChaCha20)
+	token, _ := j0.Generate(map[string]any{"sub": "user-42", "iat": 1500}, key)
+
+	path := filepath.Join(t.TempDir(), "epochs.txt")
+	writeFile(t, path, "# password resets\nuser-42 1000\n")
+
+	store, err := gojwe.NewFileSessionEpochStore(path, 0)
+	if err != nil {
+		t.Fatalf("NewFileSessionEpochStore() error = %v", err)
+	}
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithSessionEpochs(store))
+	if _, err := j.Parse(token, key); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	writeFile(t, path, "user-42 1000\nuser-42 2000\n")
+	if _, err := j.Parse(token, key); !errors.Is(err, gojwe.ErrSessionInvalidated) {
+		t.Fatalf("Parse() after reload error = %v, want ErrSessionInvalidated", err)
+	}
+}
+
+func TestFileSessionEpochStoreInvalidFile(t *testing.T) {
+	for _, content := range []string{"user-42\n", "user-42 soon\n"} {
+		path := filepath.Join(t.TempDir(), "epochs.txt")
+		writeFile(t, path, content)
+		if _, err := gojwe.NewFileSessionEpochStore(path, time.Minute); err == nil {
+			t.Fatalf("NewFileSessionEpochStore(%q) succeeded, want error", content)
+		}
+	}
+}
+
+func TestFileSessionEpochStoreFailedReloadFailsClosed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "epochs.txt")
+	writeFile(t, path, "user-42 1000\n")
+	store, err := gojwe.NewFileSessionEpochStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFileSessionEpochStore() error = %v", err)
+	}
+
+	writeFile(t, path, "user-42 2000 extra\n")
+	if err := store.Reload(); err == nil {
+		t.Fatal("Reload() of an invalid file succeeded, want error")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := store.ValidAfter("user-42"); err == nil {
+			t.Fatalf("ValidAfter() #%d after a failed reload succeeded, want error", i)
+		}
+	}
+
+	writeFile(t, path, "user-42 2000\n")
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got, err := store.ValidAfter("user-42"); err != nil || got.Unix() != 2000 {
+		t.Fatalf("ValidAfter() after a successful reload = %v, %v, want 2000", got, err)
+	}
+}
@@ -34,3 +34,18 @@ func encodeHeaderB64(enc, iv, tag string) string {
 
 	return base64.RawURLEncoding.EncodeToString(json)
 }
+
+// encodeHPKEHeaderB64 builds the base64url-encoded header of an HPKE token,
+// carrying the suite identifier and the encapsulated key (ek). The header is
+// used as the AEAD associated data, so it must be fixed before encryption.
+func encodeHPKEHeaderB64(ek string) string {
+	const prefixAlg = `{"alg":"` + hpkeHeaderAlg + `","ek":"`
+	const suffix = `"}`
+
+	json := make([]byte, 0, len(prefixAlg)+len(ek)+len(suffix))
+	json = append(json, prefixAlg...)
+	json = append(json, ek...)
+	json = append(json, suffix...)
+
+	return base64.RawURLEncoding.EncodeToString(json)
+}
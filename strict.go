@@ -0,0 +1,163 @@
+package gojwe
+
+import (
+	"bytes"
+	"encoding/base64"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+// DefaultMaxJSONDepth is the deepest nesting of objects and arrays that
+// WithStrictDecoding accepts in headers and payloads, unless changed with
+// WithMaxJSONDepth.
+const DefaultMaxJSONDepth = 32
+
+// strictBase64 rejects base64url with non-zero trailing bits, so every byte
+// string has exactly one accepted encoding.
+var strictBase64 = base64.RawURLEncoding.Strict()
+
+// headerMembers are the protected header members this package understands.
+var headerMembers = map[string]bool{"alg": true, "enc": true, "iv": true, "tag": true, "ek": true, "crit": true}
+
+var errNotJSONObject = errors.New("not a JSON object")
+
+// base64 returns the base64url decoder for token segments: strict with
+// WithStrictDecoding, lenient otherwise.
+func (o options) base64() *base64.Encoding {
+	if o.strict {
+		return strictBase64
+	}
+	return base64.RawURLEncoding
+}
+
+// maxJSONDepth returns the nesting limit of strict decoding.
+func (o options) maxJSONDepth() int {
+	if o.maxDepth > 0 {
+		return o.maxDepth
+	}
+	return DefaultMaxJSONDepth
+}
+
+// decodeHeader decodes a protected header. With WithStrictDecoding the header
+// must also be a single JSON object without duplicate members, within the
+// depth limit, whose members are all understood or listed in "crit".
+func (o options) decodeHeader(b []byte, h *Header) error {
+	if o.strict {
+		if err := checkStrictJSON(b, o.maxJSONDepth()); err != nil {
+			return err
+		}
+		if err := checkHeaderMembers(b); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(b, h)
+}
+
+// checkPayload applies WithStrictDecoding to a decrypted payload.
+func (o options) checkPayload(b []byte) error {
+	if !o.strict {
+		return nil
+	}
+	if err := checkStrictJSON(b, o.maxJSONDepth()); err != nil {
+		return tokenError(StagePayload, ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// checkHeaderMembers rejects header members that are neither understood by
+// this package nor listed in "crit", and a malformed "crit".
+func checkHeaderMembers(b []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(b, &members); err != nil {
+		return err
+	}
+	var crit []string
+	if raw, ok := members["crit"]; ok {
+		if err := json.Unmarshal(raw, &crit); err != nil || len(crit) == 0 {
+			return errors.New(`"crit" must be a non-empty array of strings`)
+		}
+		for _, name := range crit {
+			if _, ok := members[name]; !ok {
+				return fmt.Errorf("critical header member %q is missing", name)
+			}
+		}
+	}
+	for name := range members {
+		if !headerMembers[name] && !containsString(crit, name) {
+			return fmt.Errorf("unknown header member %q", name)
+		}
+	}
+	return nil
+}
+
+// checkStrictJSON verifies that b is exactly one JSON object, without
+// duplicate member names at any level and nested at most maxDepth deep.
+func checkStrictJSON(b []byte, maxDepth int) error {
+	type frame struct {
+		object bool
+		key    bool // the next token of the object is a member name
+		names  map[string]struct{}
+	}
+	dec := stdjson.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var stack []*frame
+	for started := false; ; started = true {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if !started {
+				return errNotJSONObject
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if started && len(stack) == 0 {
+			return errors.New("unexpected data after the top-level object")
+		}
+		if !started && tok != stdjson.Delim('{') {
+			return errNotJSONObject
+		}
+
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch t := tok.(type) {
+		case stdjson.Delim:
+			if t == '}' || t == ']' {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			if len(stack) >= maxDepth {
+				return fmt.Errorf("JSON nested deeper than %d levels", maxDepth)
+			}
+			if top != nil && top.object {
+				top.key = true
+			}
+			f := &frame{object: t == '{', key: t == '{'}
+			if f.object {
+				f.names = map[string]struct{}{}
+			}
+			stack = append(stack, f)
+			continue
+		case string:
+			if top.object && top.key {
+				if _, dup := top.names[t]; dup {
+					return fmt.Errorf("duplicate member %q", t)
+				}
+				top.names[t] = struct{}{}
+				top.key = false
+				continue
+			}
+		}
+		if top.object {
+			top.key = true
+		}
+	}
+}
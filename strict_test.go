@@ -0,0 +1,157 @@
+package gojwe_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"github.com/prongbang/gojwe"
+)
+
+// rawToken encrypts a raw JSON payload, which Generate cannot produce when it
+// has duplicate members or is not an object.
+func rawToken(t *testing.T, j gojwe.JWE, alg string, payload string, key []byte) string {
+	t.Helper()
+	prepared, err := gojwe.PrepareKey(alg, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := gojwe.AppendGenerate(j, nil, []byte(payload), prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(token)
+}
+
+// withTrailingBits sets a trailing bit of segment i of token. Lenient decoders
+// still decode the segment to the same bytes.
+func withTrailingBits(token string, i int) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	parts := strings.Split(token, ".")
+	seg := parts[i]
+	last := strings.IndexByte(alphabet, seg[len(seg)-1])
+	parts[i] = seg[:len(seg)-1] + string(alphabet[last^1])
+	return strings.Join(parts, ".")
+}
+
+func TestStrictDecodingPayload(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	deep := `{"a":` + strings.Repeat("[", 40) + strings.Repeat("]", 40) + `}`
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		lenient := gojwe.New(alg)
+		strict := gojwe.New(alg, gojwe.WithStrictDecoding())
+
+		for name, payload := range map[string]string{
+			"duplicate":        `{"sub":"alice","sub":"admin"}`,
+			"nested duplicate": `{"ctx":{"role":"user","role":"admin"}}`,
+			"not an object":    `["sub"]`,
+			"trailing data":    `{"sub":"alice"}{"sub":"admin"}`,
+			"too deep":         deep,
+		} {
+			token := rawToken(t, lenient, alg, payload, genKey)
+			_, err := strict.Parse(token, parseKey)
+			var te *gojwe.TokenError
+			if !errors.Is(err, gojwe.ErrInvalidToken) || !errors.As(err, &te) || te.Stage != gojwe.StagePayload {
+				t.Errorf("[%s] %s: Parse() error = %v, want ErrInvalidToken at StagePayload", alg, name, err)
+			}
+		}
+
+		token := rawToken(t, lenient, alg, `{"sub":"alice","sub":"admin"}`, genKey)
+		if claims, err := lenient.Parse(token, parseKey); err != nil || claims["sub"] != "admin" {
+			t.Fatalf("[%s] lenient Parse() = %v, %v", alg, claims, err)
+		}
+
+		deeper := gojwe.New(alg, gojwe.WithStrictDecoding(), gojwe.WithMaxJSONDepth(64))
+		if _, err := deeper.Parse(rawToken(t, lenient, alg, deep, genKey), parseKey); err != nil {
+			t.Fatalf("[%s] WithMaxJSONDepth(64) Parse() error = %v", alg, err)
+		}
+
+		valid, err := strict.Generate(map[string]any{"sub": "alice", "ctx": map[string]any{"a": []any{1, 2}}}, genKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := strict.Parse(valid, parseKey); err != nil {
+			t.Fatalf("[%s] strict Parse(valid) error = %v", alg, err)
+		}
+	}
+}
+
+func TestStrictDecodingParseBytes(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	prepared, err := gojwe.PrepareKey(gojwe.ChaCha20, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithStrictDecoding())
+	token := rawToken(t, j, gojwe.ChaCha20, `{"sub":"alice","sub":"admin"}`, key)
+	if _, err := gojwe.ParseBytes(j, []byte(token), prepared); !errors.Is(err, gojwe.ErrInvalidToken) {
+		t.Fatalf("ParseBytes() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestStrictDecodingBase64(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, tc := range []struct {
+		alg     string
+		genKey  []byte
+		parse   []byte
+		segment int
+	}{
+		{gojwe.AESGCM256, key, key, 4},
+		{gojwe.HPKE, hpkePublicKey, hpkePrivateKey, 2},
+	} {
+		token, err := gojwe.New(tc.alg).Generate(map[string]any{"sub": "alice"}, tc.genKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token = withTrailingBits(token, tc.segment)
+		if _, err := gojwe.New(tc.alg).Parse(token, tc.parse); err != nil {
+			t.Fatalf("[%s] lenient Parse() error = %v", tc.alg, err)
+		}
+		if _, err := gojwe.New(tc.alg, gojwe.WithStrictDecoding()).Parse(token, tc.parse); !errors.Is(err, gojwe.ErrInvalidToken) {
+			t.Fatalf("[%s] strict Parse() error = %v, want ErrInvalidToken", tc.alg, err)
+		}
+	}
+}
+
+func TestStrictDecodingHeader(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	encrypt := func(members map[string]any) string {
+		t.Helper()
+		h := jwe.NewHeaders()
+		for name, v := range members {
+			if err := h.Set(name, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		token, err := jwe.Encrypt([]byte(`{"sub":"alice"}`),
+			jwe.WithKey(jwa.A256GCMKW, key), jwe.WithContentEncryption(jwa.A256GCM), jwe.WithProtectedHeaders(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(token)
+	}
+	strict := gojwe.New(gojwe.AESGCM256, gojwe.WithStrictDecoding())
+
+	unknown := encrypt(map[string]any{"x-route": "eu"})
+	if _, err := gojwe.New(gojwe.AESGCM256).Parse(unknown, key); err != nil {
+		t.Fatalf("lenient Parse() error = %v", err)
+	}
+	_, err := strict.Parse(unknown, key)
+	var te *gojwe.TokenError
+	if !errors.Is(err, gojwe.ErrInvalidToken) || !errors.As(err, &te) || te.Stage != gojwe.StageHeader {
+		t.Fatalf("strict Parse(unknown member) error = %v, want ErrInvalidToken at StageHeader", err)
+	}
+
+	critical := encrypt(map[string]any{"x-route": "eu", "crit": []string{"x-route"}})
+	if _, err := strict.Parse(critical, key); err != nil {
+		t.Fatalf("strict Parse(member in crit) error = %v", err)
+	}
+	missing := encrypt(map[string]any{"crit": []string{"x-route"}})
+	if _, err := strict.Parse(missing, key); !errors.Is(err, gojwe.ErrInvalidToken) {
+		t.Fatalf("strict Parse(crit member missing) error = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,216 @@
+package gojwe
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// tagRule is the requirement a `gojwe:"..."` struct tag places on one field.
+type tagRule struct {
+	index    []int
+	claim    string // JSON member name
+	required bool
+	nonempty bool
+	oneof    []string
+}
+
+// tagRules caches the rules of each claims type: []tagRule, or an error for a
+// malformed tag. Types without gojwe tags map to a nil slice, so the typed
+// paths only pay for one map lookup.
+var tagRules sync.Map // reflect.Type -> tagRulesEntry
+
+type tagRulesEntry struct {
+	rules []tagRule
+	err   error
+}
+
+// rulesOf returns the cached tag rules of the struct held by v, which may be
+// behind pointers.
+func rulesOf(v any) ([]tagRule, reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, rv, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, rv, nil
+	}
+	if e, ok := tagRules.Load(rv.Type()); ok {
+		entry := e.(tagRulesEntry)
+		return entry.rules, rv, entry.err
+	}
+	rules, err := parseTagRules(rv.Type())
+	tagRules.Store(rv.Type(), tagRulesEntry{rules: rules, err: err})
+	return rules, rv, err
+}
+
+// parseTagRules reads the gojwe tags of the exported fields of t, including
+// the fields promoted from embedded structs. Rules are comma-separated:
+//
+//	Role string `json:"role" gojwe:"required,oneof=admin user"`
+//
+// Like encoding/json, an embedded struct with a json name is a nested object
+// rather than a set of promoted claims, so the tags of its fields are ignored.
+func parseTagRules(t reflect.Type) ([]tagRule, error) {
+	var rules []tagRule
+	for _, f := range reflect.VisibleFields(t) {
+		tag, ok := f.Tag.Lookup("gojwe")
+		if !ok || !f.IsExported() || !promotedToTop(t, f.Index) {
+			continue
+		}
+		r := tagRule{index: f.Index, claim: f.Name}
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name == "-" {
+			continue
+		} else if name != "" {
+			r.claim = name
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			switch name, arg, _ := strings.Cut(strings.TrimSpace(rule), "="); name {
+			case "required":
+				r.required = true
+			case "nonempty":
+				r.nonempty = true
+			case "oneof":
+				r.oneof = strings.Fields(arg)
+			default:
+				return nil, fmt.Errorf("gojwe: unknown rule %q in the gojwe tag of %s.%s", rule, t, f.Name)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// promotedToTop reports whether the field at index is a member of the top-level
+// JSON object of t: none of the embedded fields it is promoted through has a
+// json name of its own.
+func promotedToTop(t reflect.Type, index []int) bool {
+	for i := 1; i < len(index); i++ {
+		if name, _, _ := strings.Cut(t.FieldByIndex(index[:i]).Tag.Get("json"), ","); name != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupClaim returns the claim matching name, preferring an exact match and
+// otherwise matching case-insensitively, as the JSON decoder does when it
+// fills the field.
+func lookupClaim(claims map[string]any, name string) (any, bool) {
+	if v, ok := claims[name]; ok {
+		return v, true
+	}
+	for k, v := range claims {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// checkTags enforces the gojwe struct tags of typed claims. "required" needs
+// the claim to be present and not null, "nonempty" also needs it to be
+// non-zero (a non-empty string, slice or map), and "oneof" needs a present
+// claim, or each element of an array claim, to be one of the listed values.
+func checkTags(c *claimSet, _ options, v *validation) error {
+	rules, rv, err := rulesOf(c.value)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	claims, err := c.payload()
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		raw, present := lookupClaim(claims, r.claim)
+		present = present && raw != nil
+		field, ferr := rv.FieldByIndexErr(r.index)
+		if ferr != nil { // behind a nil embedded pointer
+			present = false
+		}
+
+		switch {
+		case (r.required || r.nonempty) && !present,
+			r.nonempty && isEmpty(field):
+			if v.fail(&ClaimError{Claim: r.claim, Err: ErrMissingClaim}) {
+				return nil
+			}
+		case len(r.oneof) > 0 && present:
+			for _, s := range fieldStrings(field) {
+				if !containsString(r.oneof, s) {
+					if v.fail(&ClaimError{Claim: r.claim, Expected: r.oneof, Actual: s, Err: ErrClaimMismatch}) {
+						return nil
+					}
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkGeneratedTags enforces the gojwe tags of claims before GenerateClaims
+// encrypts them, reporting every failed field.
+func checkGeneratedTags(claims any, b []byte) error {
+	if rules, _, err := rulesOf(claims); err != nil || len(rules) == 0 {
+		return err
+	}
+	v := &validation{all: true}
+	if err := checkTags(&claimSet{raw: b, value: claims}, options{}, v); err != nil {
+		return err
+	}
+	return claimsError(v.err())
+}
+
+// hasTagRules reports whether the type of claims carries gojwe tags.
+func hasTagRules(claims any) bool {
+	rules, _, err := rulesOf(claims)
+	return err != nil || len(rules) > 0
+}
+
+// isEmpty reports whether a field holds its zero value or an empty string,
+// slice or map.
+func isEmpty(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// fieldStrings returns the values of a field as strings for "oneof": the
+// elements of a slice or array, or the single value otherwise.
+func fieldStrings(v reflect.Value) []string {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Slice, reflect.Array:
+		out := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, fieldStrings(v.Index(i))...)
+		}
+		return out
+	case reflect.String:
+		return []string{v.String()}
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
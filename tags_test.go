@@ -0,0 +1,153 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+type taggedClaims struct {
+	gojwe.RegisteredClaims
+	Role   string   `json:"role" gojwe:"required,oneof=admin user"`
+	Tenant string   `json:"tenant,omitempty" gojwe:"nonempty"`
+	Roles  []string `json:"roles,omitempty" gojwe:"oneof=read write"`
+	Level  *int     `json:"level,omitempty" gojwe:"required"`
+	Note   string   `json:"note,omitempty"`
+}
+
+func TestTagsParseClaims(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	exp := time.Now().Add(time.Hour).Unix()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithoutTimeValidation())
+		valid, err := j.Generate(map[string]any{"exp": exp, "role": "admin", "tenant": "acme", "roles": []string{"read"}, "level": 0}, genKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := gojwe.ParseClaims[taggedClaims](j, valid, parseKey)
+		if err != nil || claims.Role != "admin" || claims.Level == nil {
+			t.Fatalf("[%s] ParseClaims(valid) = %+v, %v", alg, claims, err)
+		}
+
+		for name, tc := range map[string]struct {
+			payload map[string]any
+			claim   string
+			want    error
+		}{
+			"missing required": {map[string]any{"tenant": "acme", "level": 1}, "role", gojwe.ErrMissingClaim},
+			"null required":    {map[string]any{"role": "user", "tenant": "acme", "level": nil}, "level", gojwe.ErrMissingClaim},
+			"not oneof":        {map[string]any{"role": "root", "tenant": "acme", "level": 1}, "role", gojwe.ErrClaimMismatch},
+			"array not oneof":  {map[string]any{"role": "user", "tenant": "acme", "roles": []string{"read", "delete"}, "level": 1}, "roles", gojwe.ErrClaimMismatch},
+			"empty nonempty":   {map[string]any{"role": "user", "tenant": "", "level": 1}, "tenant", gojwe.ErrMissingClaim},
+		} {
+			token, err := j.Generate(tc.payload, genKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = gojwe.ParseClaims[taggedClaims](j, token, parseKey)
+			var ce *gojwe.ClaimError
+			if !errors.Is(err, tc.want) || !errors.As(err, &ce) || ce.Claim != tc.claim {
+				t.Errorf("[%s] %s: ParseClaims() error = %v, want %v on %q", alg, name, err, tc.want, tc.claim)
+			}
+		}
+	}
+}
+
+func TestTagsAllFailures(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithAllValidationErrors())
+	token, err := j.Generate(map[string]any{"role": "root"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = gojwe.ParseClaims[taggedClaims](j, token, key)
+	var ve *gojwe.ValidationError
+	if !errors.As(err, &ve) || len(ve.Failures) != 3 {
+		t.Fatalf("ParseClaims() error = %v, want 3 failures", err)
+	}
+}
+
+func TestTagsGenerateClaims(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.XChaCha20)
+	level := 2
+	if _, err := gojwe.GenerateClaims(j, taggedClaims{Role: "user", Tenant: "acme", Level: &level}, key); err != nil {
+		t.Fatalf("GenerateClaims(valid) error = %v", err)
+	}
+
+	_, err := gojwe.GenerateClaims(j, &taggedClaims{Role: "guest"}, key)
+	var ve *gojwe.ValidationError
+	if !errors.As(err, &ve) || len(ve.Failures) != 3 {
+		t.Fatalf("GenerateClaims() error = %v, want 3 failures", err)
+	}
+	if !errors.Is(err, gojwe.ErrClaimMismatch) || !errors.Is(err, gojwe.ErrMissingClaim) {
+		t.Fatalf("GenerateClaims() error = %v, want ErrClaimMismatch and ErrMissingClaim", err)
+	}
+}
+
+func TestTagsUnknownRule(t *testing.T) {
+	type bad struct {
+		Role string `json:"role" gojwe:"mandatory"`
+	}
+	key := gojwe.MustGenerateKey()
+	if _, err := gojwe.GenerateClaims(gojwe.New(gojwe.ChaCha20), bad{Role: "x"}, key); err == nil {
+		t.Fatal("GenerateClaims() accepted an unknown tag rule")
+	}
+}
+
+type tagInner struct {
+	Scope string `json:"scope" gojwe:"required"`
+}
+
+type nestedTaggedClaims struct {
+	tagInner `json:"inner"`
+	Role     string `json:"role" gojwe:"required"`
+}
+
+func TestTagsNamedEmbeddedStruct(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	// The rule of the nested "scope" does not apply to the top-level claims.
+	token, err := j.Generate(map[string]any{"role": "user", "inner": map[string]any{}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gojwe.ParseClaims[nestedTaggedClaims](j, token, key); err != nil {
+		t.Fatalf("ParseClaims() error = %v", err)
+	}
+}
+
+func TestTagsCaseInsensitiveClaim(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	token, err := j.Generate(map[string]any{"Role": "admin", "tenant": "acme", "level": 1}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := gojwe.ParseClaims[taggedClaims](j, token, key)
+	if err != nil || claims.Role != "admin" {
+		t.Fatalf("ParseClaims() = %+v, %v", claims, err)
+	}
+}
+
+func BenchmarkParseClaimsTags(b *testing.B) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	level := 1
+	token, err := gojwe.GenerateClaims(j, taggedClaims{
+		RegisteredClaims: gojwe.RegisteredClaims{ExpiresAt: gojwe.NewNumericDate(time.Now().Add(time.Hour))},
+		Role:             "user", Tenant: "acme", Level: &level,
+	}, key)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := gojwe.ParseClaims[taggedClaims](j, token, key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package gojwe
+
+import "fmt"
+
+// Stage is the step of token processing at which Parse, Verify or ParseClaims
+// rejected a token.
+type Stage int
+
+const (
+	// StageSize covers the length limit (MaxTokenBytes) and the number of
+	// dot-separated segments.
+	StageSize Stage = iota + 1
+
+	// StageHeader covers decoding the protected header and its members, such
+	// as the nonce or encapsulated key.
+	StageHeader
+
+	// StageSignature covers the integrity check: the HMAC signature of the
+	// ChaCha algorithms and the AEAD authentication tag of every algorithm,
+	// including decoding the tag. A failed check (ErrInvalidSignature)
+	// indicates tampering or a wrong key.
+	StageSignature
+
+	// StageDecrypt covers decoding and decrypting the ciphertext.
+	StageDecrypt
+
+	// StagePayload covers decoding the decrypted JSON payload.
+	StagePayload
+
+	// StageClaims covers claim validation; the error wraps the
+	// *ValidationError or the error of the store that was consulted.
+	StageClaims
+)
+
+var stageNames = [...]string{
+	StageSize:      "size",
+	StageHeader:    "header",
+	StageSignature: "signature",
+	StageDecrypt:   "decrypt",
+	StagePayload:   "payload",
+	StageClaims:    "claims",
+}
+
+// String returns the lower-case name of the stage, e.g. "signature", suitable
+// as a metrics label.
+func (s Stage) String() string {
+	if s > 0 && int(s) < len(stageNames) {
+		return stageNames[s]
+	}
+	return fmt.Sprintf("Stage(%d)", int(s))
+}
+
+// TokenError is returned by the built-in algorithms when a token is rejected.
+// It records the Stage and wraps both the package sentinel (e.g.
+// ErrInvalidToken, ErrInvalidSignature) and the underlying cause, so errors.Is
+// keeps working while logs and metrics can tell tampering (StageSignature)
+// from malformed input sent by a buggy client (StageSize, StageHeader):
+//
+//	var terr *gojwe.TokenError
+//	if errors.As(err, &terr) {
+//	    rejected.WithLabelValues(terr.Stage.String()).Inc()
+//	}
+type TokenError struct {
+	Stage Stage
+
+	// Err is the package sentinel, or for StageClaims the validation error.
+	Err error
+
+	// Cause is the underlying error, e.g. a base64 or JSON error, or nil.
+	Cause error
+}
+
+func (e *TokenError) Error() string {
+	if e.Cause == nil {
+		return fmt.Sprintf("%v (%s)", e.Err, e.Stage)
+	}
+	return fmt.Sprintf("%v (%s): %v", e.Err, e.Stage, e.Cause)
+}
+
+// Unwrap returns the sentinel and the cause, for errors.Is and errors.As.
+func (e *TokenError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Cause}
+}
+
+// tokenError returns a *TokenError for stage.
+func tokenError(stage Stage, err, cause error) error {
+	return &TokenError{Stage: stage, Err: err, Cause: cause}
+}
+
+// claimsError wraps a validation error in a *TokenError, passing nil through.
+func claimsError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TokenError{Stage: StageClaims, Err: err}
+}
+
+// payloadError wraps a payload decoding error in a *TokenError.
+func payloadError(err error) error {
+	return &TokenError{Stage: StagePayload, Err: ErrInvalidToken, Cause: err}
+}
+
+// sizeError reports a token longer than MaxTokenBytes.
+func sizeError(n int) error {
+	return &TokenError{Stage: StageSize, Err: ErrInvalidToken, Cause: fmt.Errorf("token is %d bytes, limit is %d", n, MaxTokenBytes)}
+}
+
+// segmentsError is the cause reported when a token has the wrong number of
+// dot-separated segments.
+func segmentsError(got, want int) error {
+	return fmt.Errorf("token has %d segments, want %d", got, want)
+}
+
+// lengthError is the cause reported when a token or one of its decoded parts
+// has the wrong length.
+func lengthError(part string, got, want int) error {
+	return fmt.Errorf("%s is %d bytes, want %d", part, got, want)
+}
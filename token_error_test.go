@@ -0,0 +1,194 @@
+package gojwe_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+// tamper replaces a character in the middle of segment i of token.
+func tamper(token string, i int) string {
+	parts := strings.Split(token, ".")
+	b := []byte(parts[i])
+	mid := len(b) / 2
+	if b[mid] == 'A' {
+		b[mid] = 'B'
+	} else {
+		b[mid] = 'A'
+	}
+	parts[i] = string(b)
+	return strings.Join(parts, ".")
+}
+
+func TestTokenErrorStages(t *testing.T) {
+	type keys struct{ enc, dec, wrong []byte }
+	symmetric := func() keys { return keys{gojwe.MustGenerateKey(), nil, gojwe.MustGenerateKey()} }
+	algs := map[string]struct {
+		keys       keys
+		cipherPart int
+	}{
+		gojwe.AESGCM256: {symmetric(), 3},
+		gojwe.ChaCha20:  {symmetric(), 1},
+		gojwe.XChaCha20: {symmetric(), 1},
+		gojwe.HPKE:      {keys{hpkePublicKey, hpkePrivateKey, gojwe.MustGenerateKey()}, 1},
+	}
+
+	for alg, tc := range algs {
+		dec := tc.keys.dec
+		if dec == nil {
+			dec = tc.keys.enc
+		}
+		j := gojwe.New(alg)
+		valid, _ := j.Generate(map[string]any{"sub": "x"}, tc.keys.enc)
+		expired, _ := j.Generate(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, tc.keys.enc)
+		notObject, _ := gojwe.GenerateClaims(j, "just a string", tc.keys.enc)
+
+		tests := []struct {
+			name  string
+			token string
+			key   []byte
+			stage gojwe.Stage
+			want  error
+		}{
+			{"too long", strings.Repeat("a", gojwe.MaxTokenBytes+1), dec, gojwe.StageSize, gojwe.ErrInvalidToken},
+			{"segments", "a.b", dec, gojwe.StageSize, gojwe.ErrInvalidToken},
+			{"header", "!" + valid[1:], dec, gojwe.StageHeader, gojwe.ErrInvalidToken},
+			{"tampered", tamper(valid, tc.cipherPart), dec, gojwe.StageSignature, gojwe.ErrInvalidSignature},
+			{"wrong key", valid, tc.keys.wrong, gojwe.StageSignature, gojwe.ErrInvalidSignature},
+			{"payload", notObject, dec, gojwe.StagePayload, gojwe.ErrInvalidToken},
+			{"claims", expired, dec, gojwe.StageClaims, gojwe.ErrTokenExpired},
+		}
+		for _, tt := range tests {
+			_, err := j.Parse(tt.token, tt.key)
+			var terr *gojwe.TokenError
+			if !errors.As(err, &terr) {
+				t.Fatalf("[%s] %s: Parse() error = %v, want a TokenError", alg, tt.name, err)
+			}
+			if terr.Stage != tt.stage || !errors.Is(err, tt.want) {
+				t.Fatalf("[%s] %s: Parse() error = %v at stage %s, want %v at stage %s", alg, tt.name, err, terr.Stage, tt.want, tt.stage)
+			}
+		}
+
+		// The typed path classifies the same way.
+		_, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, tamper(valid, tc.cipherPart), dec)
+		var terr *gojwe.TokenError
+		if !errors.As(err, &terr) || terr.Stage != gojwe.StageSignature {
+			t.Fatalf("[%s] ParseClaims() error = %v, want a signature TokenError", alg, err)
+		}
+		_, err = gojwe.ParseClaims[gojwe.RegisteredClaims](j, expired, dec)
+		var verr *gojwe.ValidationError
+		if !errors.As(err, &terr) || terr.Stage != gojwe.StageClaims || !errors.As(err, &verr) {
+			t.Fatalf("[%s] ParseClaims() error = %v, want a claims TokenError", alg, err)
+		}
+	}
+}
+
+func TestTokenErrorCause(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	_, err := j.Parse("!!!.b.c", key)
+
+	var terr *gojwe.TokenError
+	if !errors.As(err, &terr) || terr.Cause == nil {
+		t.Fatalf("Parse() error = %v, want a TokenError with a cause", err)
+	}
+	if got := terr.Stage.String(); got != "header" {
+		t.Fatalf("Stage.String() = %q, want header", got)
+	}
+	if !strings.Contains(err.Error(), "(header)") || !strings.Contains(err.Error(), "illegal base64") {
+		t.Fatalf("Error() = %q", err)
+	}
+}
+
+func TestParseBytesTokenError(t *testing.T) {
+	raw := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.XChaCha20)
+	key, _ := gojwe.PrepareKey(gojwe.XChaCha20, raw)
+	token, _ := j.Generate(map[string]any{"sub": "x"}, raw)
+
+	tests := map[string]struct {
+		token string
+		stage gojwe.Stage
+	}{
+		"segments": {"a.b.c.d", gojwe.StageSize},
+		"tampered": {tamper(token, 1), gojwe.StageSignature},
+	}
+	for name, tt := range tests {
+		_, err := gojwe.ParseBytes(j, []byte(tt.token), key)
+		var terr *gojwe.TokenError
+		if !errors.As(err, &terr) || terr.Stage != tt.stage {
+			t.Fatalf("%s: ParseBytes() error = %v, want stage %s", name, err, tt.stage)
+		}
+	}
+}
+
+// withHeaderTag replaces the "tag" header member of a ChaCha token and signs
+// it again, so that the tag itself is what fails.
+func withHeaderTag(t *testing.T, alg string, key []byte, token, tag string) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := map[string]any{}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		t.Fatal(err)
+	}
+	header["tag"] = tag
+	headerJSON, _ = json.Marshal(header)
+	parts[0] = base64.RawURLEncoding.EncodeToString(headerJSON)
+	return gojwe.SignToken(alg, key, strings.Join(parts, "."))
+}
+
+// withSegment replaces segment i of token.
+func withSegment(token string, i int, segment string) string {
+	parts := strings.Split(token, ".")
+	parts[i] = segment
+	return strings.Join(parts, ".")
+}
+
+func TestTokenErrorStagesAuthentication(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg)
+		valid, _ := j.Generate(map[string]any{"sub": "x"}, genKey)
+
+		var badTag, badAEAD string
+		switch alg {
+		case gojwe.AESGCM256:
+			badTag, badAEAD = withSegment(valid, 4, "!!!!"), tamper(valid, 3)
+		case gojwe.HPKE:
+			badTag, badAEAD = withSegment(valid, 2, "!!!!"), tamper(valid, 1)
+		default:
+			// The HMAC signature is valid, so the AEAD check is what fails.
+			badTag = withHeaderTag(t, alg, key, valid, "!!!!")
+			badAEAD = gojwe.SignToken(alg, key, tamper(valid, 1))
+		}
+		prepared, _ := gojwe.PrepareKey(alg, parseKey)
+
+		for _, tt := range []struct {
+			name  string
+			token string
+			want  error
+		}{
+			{"tag encoding", badTag, gojwe.ErrInvalidToken},
+			{"authentication", badAEAD, gojwe.ErrInvalidSignature},
+		} {
+			_, err := j.Parse(tt.token, parseKey)
+			_, errBytes := gojwe.ParseBytes(j, []byte(tt.token), prepared)
+			for _, err := range []error{err, errBytes} {
+				var terr *gojwe.TokenError
+				if !errors.As(err, &terr) || terr.Stage != gojwe.StageSignature || !errors.Is(err, tt.want) {
+					t.Fatalf("[%s] %s: error = %v, want %v at stage signature", alg, tt.name, err, tt.want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package gojwe
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Token types set in the "typ" claim of the tokens minted by TokenIssuer.
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+)
+
+// familyClaim is the claim holding the identifier of the token family a pair
+// belongs to.
+const familyClaim = "fid"
+
+// TokenPair is an access token and the refresh token that renews it.
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+}
+
+// FamilyStore tracks token families: the chain of refresh tokens that
+// descends from one login through rotation. Implement it on top of a shared
+// database when several instances rotate tokens; NewMemoryFamilyStore
+// provides a process-local version.
+type FamilyStore interface {
+	// Start records a new family whose current refresh token is jti, kept
+	// until the refresh token expires. now is the issuance time (see
+	// WithClock), for dropping families that have expired.
+	Start(family, jti string, until, now time.Time) error
+
+	// Rotate atomically replaces the current refresh token of family, which
+	// must be used, with next. It returns ErrRefreshTokenReused when used is
+	// not the current token and ErrTokenFamilyRevoked when the family is
+	// revoked or unknown.
+	Rotate(family, used, next string, until time.Time) error
+
+	// Revoke revokes every token of family.
+	Revoke(family string) error
+
+	// IsRevoked reports whether family has been revoked or is unknown, e.g.
+	// forgotten once its last refresh token expired or lost with a restart.
+	// Like Rotate, it fails closed: access tokens of a family the store does
+	// not know are rejected.
+	IsRevoked(family string) (bool, error)
+}
+
+// PairOption configures a TokenIssuer.
+type PairOption func(*TokenIssuer)
+
+// WithAccessTokenTTL sets the lifetime of access tokens (default 15 minutes).
+// Access tokens never outlive the refresh token of their pair, since their
+// family is forgotten once that expires.
+func WithAccessTokenTTL(d time.Duration) PairOption {
+	return func(ti *TokenIssuer) { ti.accessTTL = d }
+}
+
+// WithRefreshTokenTTL sets the lifetime of refresh tokens (default 30 days).
+// Each rotation issues a refresh token with a full lifetime.
+func WithRefreshTokenTTL(d time.Duration) PairOption {
+	return func(ti *TokenIssuer) { ti.refreshTTL = d }
+}
+
+// WithAccessAudience sets the "aud" of access tokens, which ParseAccess then
+// requires.
+func WithAccessAudience(aud ...string) PairOption {
+	return func(ti *TokenIssuer) { ti.accessAud = aud }
+}
+
+// WithRefreshAudience sets the "aud" of refresh tokens, typically the token
+// endpoint, which Rotate then requires.
+func WithRefreshAudience(aud ...string) PairOption {
+	return func(ti *TokenIssuer) { ti.refreshAud = aud }
+}
+
+// TokenIssuer mints access/refresh token pairs and rotates refresh tokens
+// following the OAuth 2.0 Security BCP: every refresh token can be used once,
+// and presenting an already rotated one revokes its whole family, so a stolen
+// refresh token stops working for the thief and the victim alike.
+//
+// Both tokens carry the caller's claims plus "typ" (AccessTokenType or
+// RefreshTokenType), "aud", "iat", "exp", "jti" and "fid", the family
+// identifier. They are encrypted and validated with the same JWE instance and
+// key; for HPKE the key is the private key, from which the public key to
+// encrypt to is derived. The audience options of the instance (WithAudience,
+// WithAnyAudience, WithAllAudiences) are not applied to these tokens, since
+// they would hold for both types; use WithAccessAudience and
+// WithRefreshAudience instead.
+type TokenIssuer struct {
+	j     JWE
+	key   []byte
+	store FamilyStore
+
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	accessAud  []string
+	refreshAud []string
+}
+
+// NewTokenIssuer returns a TokenIssuer minting tokens with j and key and
+// tracking families in store.
+func NewTokenIssuer(j JWE, key []byte, store FamilyStore, opts ...PairOption) *TokenIssuer {
+	ti := &TokenIssuer{
+		j:          j,
+		key:        append([]byte(nil), key...),
+		store:      store,
+		accessTTL:  15 * time.Minute,
+		refreshTTL: 30 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(ti)
+	}
+	return ti
+}
+
+// Issue starts a new token family, e.g. after login, and returns its first
+// pair. claims, typically "sub" and scopes, are copied into both tokens.
+func (ti *TokenIssuer) Issue(claims map[string]any) (*TokenPair, error) {
+	o := ti.options()
+	family, err := UUIDv4(o.now(), o.randReader())
+	if err != nil {
+		return nil, err
+	}
+	pair, refreshID, err := ti.mint(claims, family)
+	if err != nil {
+		return nil, err
+	}
+	if err := ti.store.Start(family, refreshID, pair.RefreshExpiresAt, o.now()); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Rotate exchanges a refresh token for a new pair carrying the same claims.
+// The presented refresh token cannot be used again: doing so fails with
+// ErrRefreshTokenReused and revokes the family, so that the pair issued by
+// the first rotation stops working too.
+func (ti *TokenIssuer) Rotate(refreshToken string) (*TokenPair, error) {
+	// Reuse is detected by the family store rather than the replay check of
+	// the JWE, so that it revokes the family.
+	claims, _, err := ti.parse(refreshToken, RefreshTokenType, ti.refreshAud)
+	if err != nil {
+		return nil, err
+	}
+	family, _ := claims[familyClaim].(string)
+	used, _ := claims["jti"].(string)
+	if used == "" {
+		return nil, claimFailure(&ClaimError{Claim: "jti", Err: ErrMissingClaim})
+	}
+
+	pair, next, err := ti.mint(claims, family)
+	if err != nil {
+		return nil, err
+	}
+	err = ti.store.Rotate(family, used, next, pair.RefreshExpiresAt)
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if err := ti.store.Revoke(family); err != nil {
+			return nil, err
+		}
+	}
+	if errors.Is(err, ErrRefreshTokenReused) || errors.Is(err, ErrTokenFamilyRevoked) {
+		return nil, claimFailure(&ClaimError{Claim: "jti", Actual: used, Err: err})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// ParseAccess parses and validates an access token minted by ti, rejecting
+// refresh tokens and tokens of revoked families.
+func (ti *TokenIssuer) ParseAccess(token string) (map[string]any, error) {
+	claims, consume, err := ti.parse(token, AccessTokenType, ti.accessAud)
+	if err != nil {
+		return nil, err
+	}
+	if err := consume(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// RevokeFamily revokes every token descending from the login that produced
+// the given token, e.g. on logout. token may be an access or refresh token.
+func (ti *TokenIssuer) RevokeFamily(token string) error {
+	claims, _, _, err := parseForReissue(ti.j, token, ti.key, withoutAudience)
+	if err != nil {
+		return err
+	}
+	family, _ := claims[familyClaim].(string)
+	if family == "" {
+		return claimFailure(&ClaimError{Claim: familyClaim, Err: ErrMissingClaim})
+	}
+	return ti.store.Revoke(family)
+}
+
+// parse validates token with the JWE instance, then checks its type, audience
+// and family. The replay check of the JWE is left to consume; see
+// parseForReissue.
+func (ti *TokenIssuer) parse(token, typ string, aud []string) (claims map[string]any, consume func() error, err error) {
+	claims, _, consume, err = parseForReissue(ti.j, token, ti.key, withoutAudience)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims["typ"] != typ {
+		return nil, nil, claimFailure(&ClaimError{Claim: "typ", Expected: typ, Actual: claims["typ"], Err: ErrInvalidTokenType})
+	}
+	for _, want := range aud {
+		if !audienceContains(claims["aud"], want, MatchExact) {
+			return nil, nil, claimFailure(&ClaimError{Claim: "aud", Expected: aud, Actual: claims["aud"], Err: ErrInvalidAudience})
+		}
+	}
+	family, _ := claims[familyClaim].(string)
+	if family == "" {
+		return nil, nil, claimFailure(&ClaimError{Claim: familyClaim, Err: ErrMissingClaim})
+	}
+	revoked, err := ti.store.IsRevoked(family)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, claimFailure(&ClaimError{Claim: familyClaim, Actual: family, Err: ErrTokenFamilyRevoked})
+	}
+	return claims, consume, nil
+}
+
+// withoutAudience drops the audiences of the JWE instance, which would apply
+// to access and refresh tokens alike; parse checks the audience of each token
+// type instead.
+func withoutAudience(o *options) {
+	o.audiences, o.allAudiences = nil, false
+}
+
+// options returns the options of the issuer's JWE, for its clock and random
+// source.
+func (ti *TokenIssuer) options() options {
+	if rc, ok := ti.j.(rawCodec); ok {
+		return rc.getOptions()
+	}
+	return defaultOptions()
+}
+
+// mint issues a pair for family carrying claims, and returns the "jti" of the
+// refresh token.
+func (ti *TokenIssuer) mint(claims map[string]any, family string) (*TokenPair, string, error) {
+	o := ti.options()
+	now := o.now()
+	pair := &TokenPair{
+		AccessExpiresAt:  o.truncate(now.Add(min(ti.accessTTL, ti.refreshTTL))),
+		RefreshExpiresAt: o.truncate(now.Add(ti.refreshTTL)),
+	}
+
+	var refreshID string
+	for _, t := range []struct {
+		typ   string
+		aud   []string
+		exp   time.Time
+		token *string
+	}{
+		{AccessTokenType, ti.accessAud, pair.AccessExpiresAt, &pair.AccessToken},
+		{RefreshTokenType, ti.refreshAud, pair.RefreshExpiresAt, &pair.RefreshToken},
+	} {
+		id, err := UUIDv4(now, o.randReader())
+		if err != nil {
+			return nil, "", err
+		}
+		c := make(map[string]any, len(claims)+6)
+		for name, v := range claims {
+			c[name] = v
+		}
+		delete(c, "aud")
+		delete(c, "nbf")
+		if len(t.aud) > 0 {
+			c["aud"] = ClaimStrings(t.aud)
+		}
+		c["typ"] = t.typ
+		c["iat"] = o.numericDate(now)
+		c["exp"] = o.numericDate(t.exp)
+		c["jti"] = id
+		c[familyClaim] = family
+
+		if *t.token, err = reissue(ti.j, c, ti.key); err != nil {
+			return nil, "", err
+		}
+		refreshID = id
+	}
+	return pair, refreshID, nil
+}
+
+// familyEntry is the state of one token family in a MemoryFamilyStore.
+type familyEntry struct {
+	current string
+	until   time.Time
+	revoked bool
+}
+
+// MemoryFamilyStore is an in-memory FamilyStore. Families are dropped once
+// their last refresh token has expired. It is safe for concurrent use.
+type MemoryFamilyStore struct {
+	mu       sync.Mutex
+	families map[string]*familyEntry
+	starts   int
+}
+
+// NewMemoryFamilyStore returns an empty in-memory family store.
+func NewMemoryFamilyStore() *MemoryFamilyStore {
+	return &MemoryFamilyStore{families: map[string]*familyEntry{}}
+}
+
+// Start implements FamilyStore.
+func (s *MemoryFamilyStore) Start(family, jti string, until, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.families[family] = &familyEntry{current: jti, until: until}
+
+	s.starts++
+	if s.starts >= revokerSweepEvery {
+		s.starts = 0
+		for id, f := range s.families {
+			if !now.Before(f.until) {
+				delete(s.families, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Rotate implements FamilyStore.
+func (s *MemoryFamilyStore) Rotate(family, used, next string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.families[family]
+	if !ok || f.revoked {
+		return ErrTokenFamilyRevoked
+	}
+	if f.current != used {
+		return ErrRefreshTokenReused
+	}
+	f.current, f.until = next, until
+	return nil
+}
+
+// Revoke implements FamilyStore. The family is remembered as revoked until
+// its last refresh token expires.
+func (s *MemoryFamilyStore) Revoke(family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.families[family]; ok {
+		f.revoked = true
+	}
+	return nil
+}
+
+// IsRevoked implements FamilyStore.
+func (s *MemoryFamilyStore) IsRevoked(family string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.families[family]
+	return !ok || f.revoked, nil
+}
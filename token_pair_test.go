@@ -0,0 +1,228 @@
+package gojwe_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func newTokenIssuer(t *testing.T, alg string) (*gojwe.TokenIssuer, *gojwe.FakeClock) {
+	t.Helper()
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	return gojwe.NewTokenIssuer(gojwe.New(alg, gojwe.WithClock(clock)), gojwe.MustGenerateKey(), gojwe.NewMemoryFamilyStore(),
+		gojwe.WithAccessTokenTTL(10*time.Minute),
+		gojwe.WithRefreshTokenTTL(24*time.Hour),
+		gojwe.WithAccessAudience("api"),
+		gojwe.WithRefreshAudience("token-endpoint"),
+	), clock
+}
+
+func TestTokenIssuerPair(t *testing.T) {
+	for _, alg := range allAlgs() {
+		ti, clock := newTokenIssuer(t, alg)
+		pair, err := ti.Issue(map[string]any{"sub": "user-1", "scope": "read"})
+		if err != nil {
+			t.Fatalf("[%s] Issue() error = %v", alg, err)
+		}
+		now := clock.Now()
+		if !pair.AccessExpiresAt.Equal(now.Add(10*time.Minute)) || !pair.RefreshExpiresAt.Equal(now.Add(24*time.Hour)) {
+			t.Fatalf("[%s] expiries = %v, %v", alg, pair.AccessExpiresAt, pair.RefreshExpiresAt)
+		}
+
+		claims, err := ti.ParseAccess(pair.AccessToken)
+		if err != nil {
+			t.Fatalf("[%s] ParseAccess() error = %v", alg, err)
+		}
+		if claims["typ"] != gojwe.AccessTokenType || claims["sub"] != "user-1" || claims["scope"] != "read" {
+			t.Fatalf("[%s] access claims = %v", alg, claims)
+		}
+
+		if _, err := ti.ParseAccess(pair.RefreshToken); !errors.Is(err, gojwe.ErrInvalidTokenType) {
+			t.Fatalf("[%s] ParseAccess(refresh) error = %v, want ErrInvalidTokenType", alg, err)
+		}
+		if _, err := ti.Rotate(pair.AccessToken); !errors.Is(err, gojwe.ErrInvalidTokenType) {
+			t.Fatalf("[%s] Rotate(access) error = %v, want ErrInvalidTokenType", alg, err)
+		}
+	}
+}
+
+func TestTokenIssuerRotation(t *testing.T) {
+	ti, clock := newTokenIssuer(t, gojwe.XChaCha20)
+	first, err := ti.Issue(map[string]any{"sub": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour)
+	second, err := ti.Rotate(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("Rotate() returned the same refresh token")
+	}
+	if !second.RefreshExpiresAt.Equal(clock.Now().Add(24 * time.Hour)) {
+		t.Fatalf("RefreshExpiresAt = %v", second.RefreshExpiresAt)
+	}
+	claims, err := ti.ParseAccess(second.AccessToken)
+	if err != nil || claims["sub"] != "user-1" {
+		t.Fatalf("ParseAccess() = %v, %v", claims, err)
+	}
+
+	third, err := ti.Rotate(second.RefreshToken)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	// Replaying a rotated refresh token revokes the whole family.
+	if _, err := ti.Rotate(first.RefreshToken); !errors.Is(err, gojwe.ErrRefreshTokenReused) {
+		t.Fatalf("Rotate(reused) error = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := ti.Rotate(third.RefreshToken); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("Rotate(latest) error = %v, want ErrTokenFamilyRevoked", err)
+	}
+	if _, err := ti.ParseAccess(third.AccessToken); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("ParseAccess() error = %v, want ErrTokenFamilyRevoked", err)
+	}
+
+	// Other families are unaffected.
+	other, err := ti.Issue(map[string]any{"sub": "user-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.Rotate(other.RefreshToken); err != nil {
+		t.Fatalf("Rotate(other family) error = %v", err)
+	}
+}
+
+func TestTokenIssuerRevokeFamily(t *testing.T) {
+	ti, _ := newTokenIssuer(t, gojwe.ChaCha20)
+	pair, err := ti.Issue(map[string]any{"sub": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ti.RevokeFamily(pair.AccessToken); err != nil {
+		t.Fatalf("RevokeFamily() error = %v", err)
+	}
+	if _, err := ti.ParseAccess(pair.AccessToken); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("ParseAccess() error = %v, want ErrTokenFamilyRevoked", err)
+	}
+	if _, err := ti.Rotate(pair.RefreshToken); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("Rotate() error = %v, want ErrTokenFamilyRevoked", err)
+	}
+}
+
+func TestTokenIssuerExpiredRefreshToken(t *testing.T) {
+	ti, clock := newTokenIssuer(t, gojwe.ChaCha20)
+	pair, err := ti.Issue(map[string]any{"sub": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(25 * time.Hour)
+	if _, err := ti.Rotate(pair.RefreshToken); !errors.Is(err, gojwe.ErrTokenExpired) {
+		t.Fatalf("Rotate() error = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestTokenIssuerReuseWithReplayProtection(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithClock(clock), gojwe.WithReplayProtection(gojwe.NewMemoryReplayStore()))
+	ti := gojwe.NewTokenIssuer(j, gojwe.MustGenerateKey(), gojwe.NewMemoryFamilyStore())
+
+	first, err := ti.Issue(map[string]any{"sub": "user-1"})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	second, err := ti.Rotate(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	// Reuse is reported by the family store, which revokes the family.
+	if _, err := ti.Rotate(first.RefreshToken); !errors.Is(err, gojwe.ErrRefreshTokenReused) {
+		t.Fatalf("Rotate() of a reused token error = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := ti.Rotate(second.RefreshToken); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("Rotate() after reuse error = %v, want ErrTokenFamilyRevoked", err)
+	}
+}
+
+func TestMemoryFamilyStoreSweepUsesIssuanceTime(t *testing.T) {
+	store := gojwe.NewMemoryFamilyStore()
+	now := time.Unix(1600000000, 0)
+	until := now.Add(time.Hour)
+	for i := 0; i < 2048; i++ {
+		if err := store.Start(fmt.Sprint("family-", i), "jti", until, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Families are still live at the issuance time, whatever the wall clock.
+	if err := store.Rotate("family-0", "jti", "next", until); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+}
+
+func TestMemoryFamilyStoreUnknownFamily(t *testing.T) {
+	store := gojwe.NewMemoryFamilyStore()
+	if revoked, err := store.IsRevoked("unknown"); err != nil || !revoked {
+		t.Fatalf("IsRevoked(unknown) = %v, %v, want true", revoked, err)
+	}
+	if err := store.Rotate("unknown", "a", "b", time.Now().Add(time.Hour)); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("Rotate(unknown) error = %v, want ErrTokenFamilyRevoked", err)
+	}
+
+	// A fresh store, e.g. after a restart, rejects the tokens of earlier families.
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20)
+	pair, err := gojwe.NewTokenIssuer(j, key, gojwe.NewMemoryFamilyStore()).Issue(map[string]any{"sub": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := gojwe.NewTokenIssuer(j, key, store)
+	if _, err := restarted.ParseAccess(pair.AccessToken); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("ParseAccess() error = %v, want ErrTokenFamilyRevoked", err)
+	}
+	if _, err := restarted.Rotate(pair.RefreshToken); !errors.Is(err, gojwe.ErrTokenFamilyRevoked) {
+		t.Fatalf("Rotate() error = %v, want ErrTokenFamilyRevoked", err)
+	}
+}
+
+func TestTokenIssuerAccessTTLCappedByRefreshTTL(t *testing.T) {
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	ti := gojwe.NewTokenIssuer(gojwe.New(gojwe.ChaCha20, gojwe.WithClock(clock)), gojwe.MustGenerateKey(), gojwe.NewMemoryFamilyStore(),
+		gojwe.WithAccessTokenTTL(2*time.Hour), gojwe.WithRefreshTokenTTL(time.Hour))
+	pair, err := ti.Issue(map[string]any{"sub": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pair.AccessExpiresAt.Equal(pair.RefreshExpiresAt) {
+		t.Fatalf("AccessExpiresAt = %v, want %v", pair.AccessExpiresAt, pair.RefreshExpiresAt)
+	}
+}
+
+func TestTokenIssuerIgnoresInstanceAudience(t *testing.T) {
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithAudience("api"))
+	ti := gojwe.NewTokenIssuer(j, gojwe.MustGenerateKey(), gojwe.NewMemoryFamilyStore(),
+		gojwe.WithAccessAudience("api"), gojwe.WithRefreshAudience("token"))
+	pair, err := ti.Issue(map[string]any{"sub": "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ti.ParseAccess(pair.AccessToken); err != nil {
+		t.Fatalf("ParseAccess() error = %v", err)
+	}
+	next, err := ti.Rotate(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if err := ti.RevokeFamily(next.RefreshToken); err != nil {
+		t.Fatalf("RevokeFamily() error = %v", err)
+	}
+	// The per-type audience is still enforced.
+	if _, err := ti.Rotate(next.AccessToken); err == nil {
+		t.Fatal("Rotate(access) succeeded")
+	}
+}
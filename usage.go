@@ -0,0 +1,81 @@
+package gojwe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// UsageStore counts how many tokens each key has encrypted. Implement it on top
+// of a database or cache to keep the counters across restarts and share them
+// between instances; NewMemoryUsageStore provides a process-local version.
+//
+// Keys are identified by KeyID, never by their raw bytes.
+type UsageStore interface {
+	// Increment atomically records one more use of keyID and returns the new
+	// total.
+	Increment(keyID string) (uint64, error)
+}
+
+// KeyID returns a stable, one-way identifier for key, suitable for logs and for
+// keying usage counters. It does not reveal the key.
+func KeyID(key []byte) string {
+	h := sha256.New()
+	h.Write([]byte("gojwe key id"))
+	h.Write(key)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// MemoryUsageStore is an in-memory UsageStore. It is safe for concurrent use.
+type MemoryUsageStore struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// NewMemoryUsageStore returns an empty in-memory usage store.
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{counts: map[string]uint64{}}
+}
+
+// Increment implements UsageStore.
+func (s *MemoryUsageStore) Increment(keyID string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[keyID]++
+	return s.counts[keyID], nil
+}
+
+// Count returns the number of tokens recorded for keyID.
+func (s *MemoryUsageStore) Count(keyID string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[keyID]
+}
+
+// Reset clears the counter of keyID, e.g. after the key has been retired.
+func (s *MemoryUsageStore) Reset(keyID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counts, keyID)
+}
+
+// recordUsage counts one encryption with key when usage accounting is enabled,
+// firing the soft-limit callback when the soft limit is reached and refusing
+// with ErrKeyUsageExceeded once the hard limit is passed.
+func (o options) recordUsage(key *PreparedKey) error {
+	if o.usageStore == nil {
+		return nil
+	}
+	id := key.keyID()
+	n, err := o.usageStore.Increment(id)
+	if err != nil {
+		return err
+	}
+	if o.usageHardLimit > 0 && n > o.usageHardLimit {
+		return ErrKeyUsageExceeded
+	}
+	if o.usageSoftLimit > 0 && n == o.usageSoftLimit && o.onUsageSoftLimit != nil {
+		o.onUsageSoftLimit(id, n)
+	}
+	return nil
+}
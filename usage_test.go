@@ -0,0 +1,70 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestKeyUsageLimits(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	for _, alg := range allAlgs() {
+		genKey, _ := keysFor(alg, key)
+		store := gojwe.NewMemoryUsageStore()
+		var softID string
+		var softCount uint64
+		j := gojwe.New(alg,
+			gojwe.WithKeyUsage(store),
+			gojwe.WithKeyUsageSoftLimit(2, func(keyID string, count uint64) {
+				softID, softCount = keyID, count
+			}),
+			gojwe.WithKeyUsageHardLimit(3),
+		)
+
+		for i := 1; i <= 3; i++ {
+			if _, err := j.Generate(map[string]any{"sub": "x"}, genKey); err != nil {
+				t.Fatalf("[%s] Generate() #%d error = %v", alg, i, err)
+			}
+		}
+		if softID != gojwe.KeyID(genKey) || softCount != 2 {
+			t.Fatalf("[%s] soft limit callback = (%q, %d), want (%q, 2)", alg, softID, softCount, gojwe.KeyID(genKey))
+		}
+		if _, err := j.Generate(map[string]any{"sub": "x"}, genKey); !errors.Is(err, gojwe.ErrKeyUsageExceeded) {
+			t.Fatalf("[%s] Generate() past hard limit error = %v, want ErrKeyUsageExceeded", alg, err)
+		}
+
+		// Counters are per key: a fresh key is unaffected.
+		if _, err := j.Generate(map[string]any{"sub": "x"}, gojwe.MustGenerateKey()); err != nil {
+			t.Fatalf("[%s] Generate() with a new key error = %v", alg, err)
+		}
+	}
+}
+
+func TestKeyUsageCountsGenerateClaims(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	store := gojwe.NewMemoryUsageStore()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithKeyUsage(store))
+
+	_, _ = j.Generate(map[string]any{"sub": "x"}, key)
+	_, _ = gojwe.GenerateClaims(j, gojwe.RegisteredClaims{Subject: "x"}, key)
+	if got := store.Count(gojwe.KeyID(key)); got != 2 {
+		t.Fatalf("Count() = %d, want 2", got)
+	}
+
+	store.Reset(gojwe.KeyID(key))
+	if got := store.Count(gojwe.KeyID(key)); got != 0 {
+		t.Fatalf("Count() after Reset = %d, want 0", got)
+	}
+}
+
+func TestKeyUsageHardLimitWithoutStore(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.XChaCha20, gojwe.WithKeyUsageHardLimit(1))
+	if _, err := j.Generate(map[string]any{}, key); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := j.Generate(map[string]any{}, key); !errors.Is(err, gojwe.ErrKeyUsageExceeded) {
+		t.Fatalf("Generate() error = %v, want ErrKeyUsageExceeded", err)
+	}
+}
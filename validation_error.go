@@ -0,0 +1,117 @@
+package gojwe
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ClaimError describes one failed validation check. It wraps the sentinel of
+// the check (e.g. ErrTokenExpired), so errors.Is keeps working.
+type ClaimError struct {
+	// Claim is the name of the checked claim, e.g. "exp". It is empty for
+	// checks not tied to a single claim, such as custom validators.
+	Claim string
+
+	// Expected is what the check wanted, when it can be expressed as a value:
+	// the accepted issuers, audiences or scope, or for time claims the time the
+	// claim was compared with.
+	Expected any
+
+	// Actual is the value found in the token, or nil when the claim is absent.
+	Actual any
+
+	// Delta is, for time checks, how far outside the accepted window
+	// (including leeway) the token was, e.g. how long ago it expired.
+	Delta time.Duration
+
+	// Err is the sentinel or error reported by the check.
+	Err error
+}
+
+func (e *ClaimError) Error() string {
+	var mismatch *ClaimMismatchError
+	if errors.As(e.Err, &mismatch) {
+		return e.Err.Error()
+	}
+	msg := e.Err.Error()
+	if e.Claim != "" {
+		msg += ": " + e.Claim
+	}
+	switch {
+	case e.Delta != 0:
+		msg += fmt.Sprintf(" by %v", e.Delta)
+	case e.Expected != nil && e.Actual == nil:
+		msg += fmt.Sprintf(" is missing, want %v", e.Expected)
+	case e.Expected != nil:
+		msg += fmt.Sprintf(" is %v, want %v", e.Actual, e.Expected)
+	}
+	return msg
+}
+
+// Unwrap returns the error of the check.
+func (e *ClaimError) Unwrap() error { return e.Err }
+
+// ValidationError is returned by Parse, Verify and ParseClaims when the claims
+// of an authentic token fail validation. It lists the failed checks: only the
+// first one by default, or every one with WithAllValidationErrors. errors.Is
+// matches the sentinel of each failure, and errors.As finds the first
+// *ClaimError or *ClaimMismatchError:
+//
+//	var verr *gojwe.ValidationError
+//	if errors.As(err, &verr) {
+//	    for _, f := range verr.Failures {
+//	        log.Printf("%s: %v", f.Claim, f.Err)
+//	    }
+//	}
+type ValidationError struct {
+	Failures []*ClaimError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Failures) == 1 {
+		return e.Failures[0].Error()
+	}
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("gojwe: %d validation failures: %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the failures, for errors.Is and errors.As.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// validation collects the failures of one validate run.
+type validation struct {
+	all      bool
+	now      time.Time
+	failures []*ClaimError
+}
+
+// fail records a failed check and reports whether validation must stop,
+// which is after the first failure unless every failure is collected.
+func (v *validation) fail(e *ClaimError) bool {
+	v.failures = append(v.failures, e)
+	return !v.all
+}
+
+// stopped reports whether a failure has ended a fail-fast validation.
+func (v *validation) stopped() bool {
+	return !v.all && len(v.failures) > 0
+}
+
+// err returns the collected failures as a *ValidationError, or nil.
+func (v *validation) err() error {
+	if len(v.failures) == 0 {
+		return nil
+	}
+	return &ValidationError{Failures: v.failures}
+}
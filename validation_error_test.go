@@ -0,0 +1,121 @@
+package gojwe_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+func TestValidationErrorFailFast(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithClock(clock), gojwe.WithLeeway(0), gojwe.WithAudience("api"))
+		token, _ := j.Generate(map[string]any{
+			"exp": clock.Now().Add(-90 * time.Second).Unix(),
+			"aud": "web",
+		}, genKey)
+
+		_, err := j.Parse(token, parseKey)
+		var verr *gojwe.ValidationError
+		if !errors.As(err, &verr) || len(verr.Failures) != 1 {
+			t.Fatalf("[%s] Parse() error = %#v, want one failure", alg, err)
+		}
+		f := verr.Failures[0]
+		if f.Claim != "exp" || f.Delta != 90*time.Second || !errors.Is(err, gojwe.ErrTokenExpired) {
+			t.Fatalf("[%s] failure = %+v", alg, f)
+		}
+		if errors.Is(err, gojwe.ErrInvalidAudience) {
+			t.Fatalf("[%s] fail-fast error also reports the audience", alg)
+		}
+		if !strings.Contains(err.Error(), "1m30s") {
+			t.Fatalf("[%s] Error() = %q, want the delta", alg, err)
+		}
+	}
+}
+
+func TestWithAllValidationErrors(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	clock := gojwe.NewFakeClock(time.Unix(1700000000, 0))
+	store := gojwe.NewMemoryReplayStore()
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg,
+			gojwe.WithClock(clock),
+			gojwe.WithAllValidationErrors(),
+			gojwe.WithIssuer("auth"),
+			gojwe.WithAudience("api"),
+			gojwe.WithRequiredClaims("sub", "tenant"),
+			gojwe.WithReplayProtection(store),
+		)
+		claims := map[string]any{
+			"exp": clock.Now().Add(-time.Hour).Unix(),
+			"iss": "evil",
+			"aud": []string{"web"},
+			"jti": "once-" + alg,
+		}
+		token, _ := j.Generate(claims, genKey)
+
+		for _, parse := range []func() error{
+			func() error { _, err := j.Parse(token, parseKey); return err },
+			func() error { _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, token, parseKey); return err },
+		} {
+			err := parse()
+			var verr *gojwe.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("[%s] error = %v, want a ValidationError", alg, err)
+			}
+			var got []string
+			for _, f := range verr.Failures {
+				got = append(got, f.Claim)
+			}
+			if strings.Join(got, ",") != "exp,sub,tenant,iss,aud" {
+				t.Fatalf("[%s] failed claims = %v", alg, got)
+			}
+			for _, want := range []error{gojwe.ErrTokenExpired, gojwe.ErrMissingClaim, gojwe.ErrInvalidIssuer, gojwe.ErrInvalidAudience} {
+				if !errors.Is(err, want) {
+					t.Fatalf("[%s] errors.Is(%v) = false", alg, want)
+				}
+			}
+			// An invalid token is never recorded as used.
+			if errors.Is(err, gojwe.ErrTokenReplayed) {
+				t.Fatalf("[%s] invalid token was recorded by replay protection", alg)
+			}
+		}
+		if iss := verrFailure(t, j, token, parseKey, "iss"); iss.Actual != "evil" {
+			t.Fatalf("[%s] iss failure = %+v", alg, iss)
+		}
+	}
+}
+
+func TestValidationErrorWrapsMismatch(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	j := gojwe.New(gojwe.ChaCha20, gojwe.WithSubject("svc"), gojwe.WithAllValidationErrors())
+	token, _ := j.Generate(map[string]any{"sub": "other"}, key)
+
+	_, err := j.Parse(token, key)
+	var mismatch *gojwe.ClaimMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, gojwe.ErrInvalidSubject) {
+		t.Fatalf("Parse() error = %v, want a ClaimMismatchError", err)
+	}
+}
+
+func verrFailure(t *testing.T, j gojwe.JWE, token string, key []byte, claim string) *gojwe.ClaimError {
+	t.Helper()
+	_, err := j.Parse(token, key)
+	var verr *gojwe.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Parse() error = %v, want a ValidationError", err)
+	}
+	for _, f := range verr.Failures {
+		if f.Claim == claim {
+			return f
+		}
+	}
+	t.Fatalf("no failure for %s in %v", claim, err)
+	return nil
+}
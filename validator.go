@@ -0,0 +1,33 @@
+package gojwe
+
+// Validator is implemented by claim types with application-specific rules,
+// e.g. "role must be admin or user". ParseClaims and ParseClaimsWithKey call
+// Validate after the registered claims have been checked, so an invalid token
+// is rejected inside Parse instead of in every handler.
+type Validator interface {
+	Validate() error
+}
+
+// checkCustom runs the Validator of the typed claims, if any, and then every
+// function registered with WithValidator. On the typed paths the payload is
+// only decoded into a map when such functions are configured.
+func checkCustom(c *claimSet, opts options, v *validation) error {
+	if val, ok := c.value.(Validator); ok {
+		if err := val.Validate(); err != nil && v.fail(&ClaimError{Err: err}) {
+			return nil
+		}
+	}
+	if len(opts.validators) == 0 {
+		return nil
+	}
+	claims, err := c.payload()
+	if err != nil {
+		return err
+	}
+	for _, fn := range opts.validators {
+		if err := fn(claims); err != nil && v.fail(&ClaimError{Err: err}) {
+			return nil
+		}
+	}
+	return nil
+}
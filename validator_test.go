@@ -0,0 +1,121 @@
+package gojwe_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prongbang/gojwe"
+)
+
+var errBadRole = errors.New("role must be admin or user")
+
+type roleClaims struct {
+	gojwe.RegisteredClaims
+	Role string `json:"role"`
+}
+
+func (c roleClaims) Validate() error {
+	if c.Role != "admin" && c.Role != "user" {
+		return errBadRole
+	}
+	return nil
+}
+
+type tenantClaims struct {
+	Tenant string `json:"tenant"`
+}
+
+func (c *tenantClaims) Validate() error {
+	if c.Tenant == "" {
+		return errors.New("tenant is required")
+	}
+	return nil
+}
+
+func TestWithValidator(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	tenant := func(claims map[string]any) error {
+		if claims["tenant"] != "acme" {
+			return errors.New("tenant must match subdomain")
+		}
+		return nil
+	}
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		j := gojwe.New(alg, gojwe.WithValidator(tenant), gojwe.WithValidator(func(claims map[string]any) error {
+			if _, ok := claims["role"]; !ok {
+				return errBadRole
+			}
+			return nil
+		}))
+
+		good, _ := j.Generate(map[string]any{"tenant": "acme", "role": "user"}, genKey)
+		wrongTenant, _ := j.Generate(map[string]any{"tenant": "evil", "role": "user"}, genKey)
+		noRole, _ := j.Generate(map[string]any{"tenant": "acme"}, genKey)
+
+		if _, err := j.Parse(good, parseKey); err != nil {
+			t.Fatalf("[%s] Parse() error = %v", alg, err)
+		}
+		if _, err := j.Parse(wrongTenant, parseKey); err == nil {
+			t.Fatalf("[%s] Parse() with the wrong tenant succeeded, want error", alg)
+		}
+		if j.Verify(wrongTenant, parseKey) {
+			t.Fatalf("[%s] Verify() with the wrong tenant = true, want false", alg)
+		}
+		if _, err := j.Parse(noRole, parseKey); !errors.Is(err, errBadRole) {
+			t.Fatalf("[%s] Parse() error = %v, want errBadRole", alg, err)
+		}
+
+		// The typed path decodes the payload for the map validators too.
+		if _, err := gojwe.ParseClaims[gojwe.RegisteredClaims](j, wrongTenant, parseKey); err == nil {
+			t.Fatalf("[%s] ParseClaims() with the wrong tenant succeeded, want error", alg)
+		}
+	}
+}
+
+// parseOnlyJWE hides the fast paths of a built-in JWE, like a custom
+// implementation that only provides the JWE interface.
+type parseOnlyJWE struct{ gojwe.JWE }
+
+func TestParseClaimsValidateErrorCustomJWE(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	bad, _ := gojwe.New(gojwe.ChaCha20).Generate(map[string]any{"role": "root"}, key)
+
+	for name, j := range map[string]gojwe.JWE{
+		"built-in": gojwe.New(gojwe.ChaCha20),
+		"custom":   parseOnlyJWE{gojwe.New(gojwe.ChaCha20)},
+	} {
+		_, err := gojwe.ParseClaims[roleClaims](j, bad, key)
+		var terr *gojwe.TokenError
+		var verr *gojwe.ValidationError
+		if !errors.Is(err, errBadRole) || !errors.As(err, &terr) || terr.Stage != gojwe.StageClaims || !errors.As(err, &verr) {
+			t.Fatalf("[%s] ParseClaims() error = %v, want a claims TokenError wrapping errBadRole", name, err)
+		}
+	}
+}
+
+func TestParseClaimsCallsValidate(t *testing.T) {
+	key := gojwe.MustGenerateKey()
+	exp := gojwe.NewNumericDate(time.Now().Add(time.Hour))
+	for _, alg := range allAlgs() {
+		genKey, parseKey := keysFor(alg, key)
+		// Validate runs even without any validation option.
+		j := gojwe.New(alg, gojwe.WithoutTimeValidation())
+
+		good, _ := gojwe.GenerateClaims(j, roleClaims{RegisteredClaims: gojwe.RegisteredClaims{ExpiresAt: exp}, Role: "admin"}, genKey)
+		bad, _ := gojwe.GenerateClaims(j, roleClaims{RegisteredClaims: gojwe.RegisteredClaims{ExpiresAt: exp}, Role: "root"}, genKey)
+
+		if claims, err := gojwe.ParseClaims[roleClaims](j, good, parseKey); err != nil || claims.Role != "admin" {
+			t.Fatalf("[%s] ParseClaims() = %+v, %v", alg, claims, err)
+		}
+		if _, err := gojwe.ParseClaims[roleClaims](j, bad, parseKey); !errors.Is(err, errBadRole) {
+			t.Fatalf("[%s] ParseClaims() error = %v, want errBadRole", alg, err)
+		}
+
+		// Pointer receivers and types without RegisteredClaims work too.
+		if _, err := gojwe.ParseClaims[tenantClaims](j, bad, parseKey); err == nil {
+			t.Fatalf("[%s] ParseClaims() without tenant succeeded, want error", alg)
+		}
+	}
+}
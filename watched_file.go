@@ -0,0 +1,108 @@
+package gojwe
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchedFile tracks the modification time and size of a line-oriented data
+// file so it can be reloaded when it changes. A failed check or load is kept
+// in err until the next successful load, so callers keep failing closed in
+// between checks.
+type watchedFile struct {
+	path     string
+	interval time.Duration
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	modTime   time.Time
+	size      int64
+	err       error
+}
+
+func newWatchedFile(path string, interval time.Duration) *watchedFile {
+	return &watchedFile{path: path, interval: interval}
+}
+
+// changed reports whether the file differs from the last load, checking at
+// most once per interval. After a failed load it reports a change at the next
+// check so that the load is retried, and the failure until then.
+func (f *watchedFile) changed() (bool, error) {
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now.Sub(f.lastCheck) < f.interval {
+		return false, f.err
+	}
+	f.lastCheck = now
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		f.err = err
+		return false, err
+	}
+	return f.err != nil || !info.ModTime().Equal(f.modTime) || info.Size() != f.size, nil
+}
+
+// load reads the file and calls entry with the whitespace-separated fields of
+// every line that is neither blank nor a '#' comment. A failure is recorded
+// for changed.
+func (f *watchedFile) load(entry func(fields []string) error) error {
+	if err := f.read(entry); err != nil {
+		f.mu.Lock()
+		f.err, f.lastCheck = err, time.Now()
+		f.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// read is load without recording a failure.
+func (f *watchedFile) read(entry func(fields []string) error) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := entry(strings.Fields(text)); err != nil {
+			return fmt.Errorf("gojwe: %s:%d: %w", f.path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.modTime, f.size, f.lastCheck, f.err = info.ModTime(), info.Size(), time.Now(), nil
+	f.mu.Unlock()
+	return nil
+}
+
+// parseUnixField parses fields[i] as Unix seconds, returning the zero time when
+// the field is absent.
+func parseUnixField(fields []string, i int) (time.Time, error) {
+	if len(fields) <= i {
+		return time.Time{}, nil
+	}
+	sec, err := strconv.ParseInt(fields[i], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid unix time %q", fields[i])
+	}
+	return time.Unix(sec, 0), nil
+}